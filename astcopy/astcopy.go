@@ -197,6 +197,18 @@ func IndexExpr(x *ast.IndexExpr) *ast.IndexExpr {
 	}
 }
 
+// IndexListExpr returns x deep copy.
+// Copy of nil argument is nil.
+func IndexListExpr(x *ast.IndexListExpr) *ast.IndexListExpr {
+	if x == nil {
+		return nil
+	}
+	return &ast.IndexListExpr{
+		X:       copyExpr(x.X),
+		Indices: ExprList(x.Indices),
+	}
+}
+
 // SliceExpr returns x deep copy.
 // Copy of nil argument is nil.
 func SliceExpr(x *ast.SliceExpr) *ast.SliceExpr {
@@ -231,8 +243,9 @@ func CallExpr(x *ast.CallExpr) *ast.CallExpr {
 		return nil
 	}
 	return &ast.CallExpr{
-		Fun:  copyExpr(x.Fun),
-		Args: ExprList(x.Args),
+		Fun:      copyExpr(x.Fun),
+		Args:     ExprList(x.Args),
+		Ellipsis: x.Ellipsis,
 	}
 }
 
@@ -346,8 +359,9 @@ func FuncType(x *ast.FuncType) *ast.FuncType {
 		return nil
 	}
 	return &ast.FuncType{
-		Params:  FieldList(x.Params),
-		Results: FieldList(x.Results),
+		TypeParams: FieldList(x.TypeParams),
+		Params:     FieldList(x.Params),
+		Results:    FieldList(x.Results),
 	}
 }
 
@@ -434,10 +448,12 @@ func TypeSpec(x *ast.TypeSpec) *ast.TypeSpec {
 		return nil
 	}
 	return &ast.TypeSpec{
-		Name:    Ident(x.Name),
-		Type:    copyExpr(x.Type),
-		Doc:     CommentGroup(x.Doc),
-		Comment: CommentGroup(x.Comment),
+		Name:       Ident(x.Name),
+		TypeParams: FieldList(x.TypeParams),
+		Assign:     x.Assign,
+		Type:       copyExpr(x.Type),
+		Doc:        CommentGroup(x.Doc),
+		Comment:    CommentGroup(x.Comment),
 	}
 }
 
@@ -865,6 +881,8 @@ func copyExpr(x ast.Expr) ast.Expr {
 		return SelectorExpr(x)
 	case *ast.IndexExpr:
 		return IndexExpr(x)
+	case *ast.IndexListExpr:
+		return IndexListExpr(x)
 	case *ast.SliceExpr:
 		return SliceExpr(x)
 	case *ast.TypeAssertExpr:
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go/build/constraint"
+	"os"
+	"strings"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// renderFlags select how mocks are rendered, and if they are verified
+type renderFlags struct {
+	style      *string
+	template   *string
+	constraint *string
+	verify     *bool
+}
+
+func addRenderFlags(flags *flag.FlagSet) renderFlags {
+	return renderFlags{
+		template:   flags.String("template", "", "render the mock with a text/template file instead of using a style"),
+		style:      flags.String("style", mockgen.DefaultStyle, "the style of the mock, one of "+strings.Join(mockgen.Styles(), ", ")),
+		constraint: flags.String("constraint", "", "the //go:build expression of the mock, e.g. linux && !race, instead of the build constraint of the source file, \"none\" leaves it out"),
+		verify:     flags.Bool("verify", false, "type check the mock against the package of the interface before writing it"),
+	}
+}
+
+// options returns the options selecting the style or the template, and
+// verifying the mocks
+func (r renderFlags) options(flags *flag.FlagSet) ([]mockgen.Option, error) {
+	styleSet := false
+	flags.Visit(func(f *flag.Flag) { styleSet = styleSet || f.Name == "style" })
+	if *r.template != "" && styleSet {
+		return nil, usagef("-template and -style can not be used together")
+	}
+	if err := oneOf("style", *r.style, mockgen.Styles()...); err != nil {
+		return nil, err
+	}
+
+	options := []mockgen.Option{mockgen.WithStyle(*r.style)}
+	if *r.verify {
+		options = append(options, mockgen.WithVerify())
+	}
+	switch *r.constraint {
+	case "":
+	case "none":
+		options = append(options, mockgen.WithBuildConstraint(""))
+	default:
+		expr, err := constraint.Parse("//go:build " + *r.constraint)
+		if err != nil {
+			return nil, usagef("invalid -constraint %q: %s", *r.constraint, err)
+		}
+		options = append(options, mockgen.WithBuildConstraint(expr.String()))
+	}
+	if *r.template != "" {
+		tmpl, err := mockgen.ParseTemplate(*r.template)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, mockgen.WithTemplate(tmpl))
+	}
+	return options, nil
+}
+
+// batchFlags are the flags used when generating the mocks of packages
+type batchFlags struct {
+	workers *int
+	check   *bool
+	cache   *string
+}
+
+// addBatchFlags adds the flags, prefix is put before the usage of the flags
+// that only apply to patterns
+func addBatchFlags(flags *flag.FlagSet, prefix string) batchFlags {
+	return batchFlags{
+		workers: flags.Int("workers", 0, prefix+"the number of packages generated at once, the number of CPUs if not set"),
+		check:   flags.Bool("check", false, prefix+"only list the mocks that are missing or out of date, and fail if there are any"),
+		cache:   flags.String("cache", "", prefix+"the directory the packages with unchanged mocks are cached in, to skip parsing them (default the user cache directory, \"off\" disables it)"),
+	}
+}
+
+func (b batchFlags) options() []mockgen.Option {
+	options := []mockgen.Option{mockgen.WithWorkers(*b.workers)}
+	cache := *b.cache
+	if cache == "" {
+		// Without a cache, packages are parsed to compare the hashes in
+		// the headers of their mocks
+		cache, _ = mockgen.DefaultCacheDir()
+	}
+	if cache != "" && cache != "off" {
+		options = append(options, mockgen.WithCache(cache))
+	}
+	return options
+}
+
+// generateFunc generates the mocks of the packages matched by patterns
+type generateFunc func(ctx context.Context, patterns []string) ([]mockgen.GeneratedFile, error)
+
+// genPackages generates the mocks of the packages matched by the patterns and
+// writes them. The paths of the written files are printed, unchanged mocks
+// are not written. Mocks that could be generated are written even if others
+// failed.
+func genPackages(e *env, generate generateFunc, generator *mockgen.Generator, patterns []string) error {
+	files, err := generate(context.Background(), patterns)
+	written, writeErr := generator.WriteFiles(files)
+	for _, path := range written {
+		fmt.Fprintln(e.stdout, relative(path))
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// checkPackages prints the mocks of the packages matched by the patterns that
+// are missing or out of date, and fails if there are any. The command is the
+// one to run to update them.
+func checkPackages(e *env, command string, check generateFunc, patterns []string) error {
+	stale, err := check(context.Background(), patterns)
+	for _, file := range stale {
+		state := "out of date"
+		if _, err := os.Stat(file.Path); os.IsNotExist(err) {
+			state = "missing"
+		}
+		fmt.Fprintf(e.stdout, "%s (%s) is %s\n", relative(file.Path), strings.Join(file.Interfaces, ", "), state)
+	}
+	if err != nil {
+		return err
+	}
+	if len(stale) > 0 {
+		mocks := "mocks are"
+		if len(stale) == 1 {
+			mocks = "mock is"
+		}
+		return fmt.Errorf("%d %s missing or out of date, run mockay %s %s", len(stale), mocks, command, strings.Join(patterns, " "))
+	}
+	return nil
+}
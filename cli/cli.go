@@ -0,0 +1,195 @@
+// Package cli implements the mockay command line interface. It runs in
+// process, with the arguments and streams given to Run, which makes it
+// possible to build binaries that register their own emitters.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// Exit codes returned by Run
+const (
+	// ExitOK is returned when the command succeeded
+	ExitOK = 0
+	// ExitError is returned when a mock could not be generated, or the
+	// command failed in another way not covered by the other codes
+	ExitError = 1
+	// ExitUsage is returned for invalid commands, flags and arguments
+	ExitUsage = 2
+	// ExitIO is returned when reading or writing a file failed
+	ExitIO = 3
+	// ExitNotFound is returned when there is no interface at the position,
+	// or with the name
+	ExitNotFound = 4
+	// ExitParse is returned when a Go file could not be parsed
+	ExitParse = 5
+	// ExitUnsupported is returned when the selected type can not be mocked,
+	// or not in the package the mock is generated in
+	ExitUnsupported = 6
+)
+
+// env is what commands read from and write to
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command is a subcommand of mockay
+type command struct {
+	name  string
+	usage string
+	run   func(e *env, args []string) error
+}
+
+// commands returns all commands, the first is the default. It is a function
+// since the commands refer to it for their usage.
+func commands() []command {
+	return []command{
+		{name: "gen", usage: "gen [options] path | pattern...", run: runGen},
+		{name: "generate", usage: "generate [options] [pattern...]", run: runGenerate},
+		{name: "watch", usage: "watch [options] [pattern...]", run: runWatch},
+		{name: "list", usage: "list [-format text|json] [path]", run: runList},
+		{name: "describe", usage: "describe [-format text|json] [-pos line:column | -name name] path", run: runDescribe},
+		{name: "lsp", usage: "lsp [-verbose] [-log-level level]", run: runLSP},
+		{name: "version", usage: "version", run: runVersion},
+	}
+}
+
+// Run runs mockay with the arguments, not including the program name, and
+// returns the exit code. The gen command is run if the first argument is not
+// a command.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			printUsage(stderr)
+			return ExitOK
+		}
+	}
+
+	cmd, known := commands()[0], false
+	if len(args) > 0 {
+		for _, c := range commands() {
+			if c.name == args[0] {
+				cmd, known = c, true
+				args = args[1:]
+				break
+			}
+		}
+		// Misspelled commands are not taken as package patterns of gen
+		if !known && !isGenArg(args[0]) {
+			fmt.Fprintf(stderr, "mockay: unknown command %q\n\n", args[0])
+			printUsage(stderr)
+			return ExitUsage
+		}
+	}
+
+	err := cmd.run(e, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	var usageErr *usageError
+	if err != nil && !(errors.As(err, &usageErr) && usageErr.reported) {
+		fmt.Fprintf(stderr, "mockay %s: %s\n", cmd.name, err)
+		if errors.Is(err, mockgen.ErrUnexported) {
+			fmt.Fprintln(stderr, "Generate the mocks in the packages of the interfaces instead, with -layout test.")
+		}
+	}
+	return exitCode(err)
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  mockay %s\n", c.usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "gen is run when no command is given. Use - as the path of gen to read the source from stdin, with -stdin-path set to its file.")
+	fmt.Fprintln(w, "Given directories or patterns like ./..., gen writes mocks of all exported interfaces of the packages.")
+	fmt.Fprintln(w, "watch keeps the mocks written that way up to date, for ./... if no patterns are given.")
+	fmt.Fprintln(w, "generate writes mocks of the interfaces marked with //mockay:generate comments, for ./... if no patterns are given.")
+	fmt.Fprintln(w, "Run mockay <command> -h for the options of a command.")
+}
+
+// usageError is an error in how a command was invoked
+type usageError struct {
+	msg string
+	// reported is set if the error has already been written to stderr
+	reported bool
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// exitCode returns the exit code of the class of the error
+func exitCode(err error) int {
+	var usageErr *usageError
+	var pathErr *fs.PathError
+	var parseErr *mockgen.ParseError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &pathErr):
+		return ExitIO
+	case errors.Is(err, mockgen.ErrInterfaceNotFound), errors.Is(err, mockgen.ErrNoTarget), errors.Is(err, mockgen.ErrInvalidPosition):
+		return ExitNotFound
+	case errors.As(err, &parseErr):
+		return ExitParse
+	case errors.Is(err, mockgen.ErrUnsupportedType), errors.Is(err, mockgen.ErrUnexported):
+		return ExitUnsupported
+	}
+	return ExitError
+}
+
+// newFlagSet creates a flag set for a command that reports errors instead of
+// exiting, with the usage of the command printed on errors
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	flags.Usage = func() {
+		for _, c := range commands() {
+			if c.name == name {
+				fmt.Fprintf(e.stderr, "Usage: mockay %s\n", c.usage)
+			}
+		}
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the arguments of a command, errors are usage errors
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	// The flag package has already printed the error and the usage
+	return &usageError{msg: err.Error(), reported: true}
+}
+
+// oneOf validates that the value of a flag is one of the allowed values
+func oneOf(flagName, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return usagef("invalid value %q for -%s, expected one of %s", value, flagName, strings.Join(allowed, ", "))
+}
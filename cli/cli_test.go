@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":         "module example.com/m\n",
+		"store/store.go": "package store\n\ntype Store interface {\n\tGet(key string) (int, error)\n}\n",
+		"empty/empty.go": "package empty\n",
+		"bad/bad.go":     "package bad\n\ntype Bad interface {\n",
+		"num/num.go":     "package num\n\ntype Number interface {\n\t~int | ~float64\n}\n",
+		"priv/priv.go":   "package priv\n\ntype Store interface {\n\tget() int\n}\n",
+		"priv/cache.go":  "package priv\n\ntype key string\n\ntype Cache interface {\n\tGet(k key) int\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := filepath.Join(dir, "store", "store.go")
+	missing := filepath.Join(dir, "missing.go")
+	// edited is store.go as modified in an editor, before it is saved
+	edited := "package store\n\ntype Item struct{}\n\ntype Store interface {\n\tGet(key string) (Item, error)\n}\n"
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+		// stdout is expected to be contained in the output, which must be
+		// empty when the command fails
+		stdout string
+		// stderr is expected to be contained in the errors of a command
+		// that fails
+		stderr string
+	}{
+		{name: "no arguments", args: nil, code: ExitUsage},
+		{name: "unknown command", args: []string{"lsit"}, code: ExitUsage},
+		{name: "help", args: []string{"help"}, code: ExitOK},
+
+		{name: "gen", args: []string{"gen", "-name", "Store", store}, code: ExitOK, stdout: "package mock"},
+		{name: "gen by default", args: []string{"-pos", "3:6", store}, code: ExitOK, stdout: "type Mocked struct"},
+		{name: "gen stdin", args: []string{"-name", "Store", "-stdin-path", store, "-"}, stdin: edited, code: ExitOK, stdout: "func (m *Mocked) Get(key string) (store.Item, error)"},
+		{name: "gen stdin without its path", args: []string{"-name", "Store", "-"}, stdin: edited, code: ExitUsage},
+		{name: "gen stdin path of a file", args: []string{"-name", "Store", "-stdin-path", store, store}, code: ExitUsage},
+		{name: "gen output in the package", args: []string{"-name", "Store", "-o", filepath.Join(dir, "store", "store_mock.go"), store}, code: ExitOK},
+		{name: "gen missing file", args: []string{"gen", "-name", "Store", missing}, code: ExitIO},
+		{name: "gen no selection", args: []string{"gen", store}, code: ExitUsage},
+		{name: "gen unknown flag", args: []string{"gen", "-nope", store}, code: ExitUsage},
+		{name: "gen pos and name", args: []string{"gen", "-pos", "3:6", "-name", "Store", store}, code: ExitUsage},
+		{name: "gen pos zero", args: []string{"gen", "-pos", "0:1", store}, code: ExitUsage},
+		{name: "gen pos not a position", args: []string{"gen", "-pos", "abc", store}, code: ExitUsage},
+		{name: "gen pos negative offset", args: []string{"gen", "-pos", "#-1", store}, code: ExitUsage},
+		{name: "gen pos encoding", args: []string{"gen", "-pos", "3:6", "-pos-encoding", "latin1", store}, code: ExitUsage},
+		{name: "gen pos offset", args: []string{"gen", "-pos", "#20", store}, code: ExitOK, stdout: "type Mocked struct"},
+		{name: "gen pos outside the file", args: []string{"gen", "-pos", "100:1", store}, code: ExitNotFound},
+		{name: "gen pos not on an interface", args: []string{"gen", "-pos", "1:1", store}, code: ExitNotFound},
+		{name: "gen unknown name", args: []string{"gen", "-name", "Nope", store}, code: ExitNotFound},
+		{name: "gen parse error", args: []string{"gen", "-name", "Bad", filepath.Join(dir, "bad", "bad.go")}, code: ExitParse},
+		{name: "gen type set", args: []string{"gen", "-name", "Number", filepath.Join(dir, "num", "num.go")}, code: ExitUnsupported},
+		{name: "gen unexported method", args: []string{"gen", "-name", "Store", filepath.Join(dir, "priv", "priv.go")}, code: ExitUnsupported, stderr: "method get is unexported"},
+		{name: "gen unexported type", args: []string{"gen", "-name", "Cache", filepath.Join(dir, "priv", "priv.go")}, code: ExitUnsupported, stderr: "with -layout test"},
+		{name: "gen unexported in its package", args: []string{"gen", "-name", "Store", "-layout", "test", filepath.Join(dir, "priv", "priv.go")}, code: ExitOK, stdout: "package priv\n"},
+		{name: "gen inplace without output", args: []string{"gen", "-name", "Store", "-inplace", store}, code: ExitUsage},
+		{name: "gen check without patterns", args: []string{"gen", "-name", "Store", "-check", store}, code: ExitUsage},
+		{name: "gen name with patterns", args: []string{"gen", "-name", "Store", dir + "/..."}, code: ExitUsage},
+		{name: "gen unknown style", args: []string{"gen", "-style", "nope", "-name", "Store", store}, code: ExitUsage, stderr: "expected one of"},
+		{name: "gen outdir with test layout", args: []string{"gen", "-layout", "test", "-outdir", "fakes", dir + "/..."}, code: ExitUsage},
+
+		{name: "generate unknown flag", args: []string{"generate", "-nope"}, code: ExitUsage},
+		{name: "generate unknown style", args: []string{"generate", "-style", "nope"}, code: ExitUsage, stderr: "expected one of"},
+		{name: "generate outdir with test layout", args: []string{"generate", "-layout", "test", "-outdir", "fakes"}, code: ExitUsage},
+
+		{name: "watch interval", args: []string{"watch", "-interval", "0"}, code: ExitUsage},
+		{name: "watch unknown style", args: []string{"watch", "-style", "nope"}, code: ExitUsage, stderr: "expected one of"},
+		{name: "watch outdir with test layout", args: []string{"watch", "-layout", "test", "-outdir", "fakes"}, code: ExitUsage},
+
+		{name: "list", args: []string{"list", dir}, code: ExitOK},
+		{name: "list json", args: []string{"list", "-format", "json", filepath.Join(dir, "empty")}, code: ExitOK, stdout: "[]"},
+		{name: "list format", args: []string{"list", "-format", "yaml", dir}, code: ExitUsage},
+		{name: "list paths", args: []string{"list", dir, dir}, code: ExitUsage},
+
+		{name: "describe", args: []string{"describe", store}, code: ExitOK, stdout: "Get(key string)"},
+		{name: "describe json", args: []string{"describe", "-format", "json", "-name", "Store", store}, code: ExitOK, stdout: `"name": "Store"`},
+		{name: "describe json empty", args: []string{"describe", "-format", "json", filepath.Join(dir, "empty", "empty.go")}, code: ExitOK, stdout: "[]"},
+		{name: "describe format", args: []string{"describe", "-format", "yaml", store}, code: ExitUsage},
+		{name: "describe no path", args: []string{"describe"}, code: ExitUsage},
+		{name: "describe missing file", args: []string{"describe", missing}, code: ExitIO},
+		{name: "describe pos", args: []string{"describe", "-pos", "3:x", store}, code: ExitUsage},
+		{name: "describe unknown name", args: []string{"describe", "-name", "Nope", store}, code: ExitNotFound},
+		{name: "describe parse error", args: []string{"describe", filepath.Join(dir, "bad", "bad.go")}, code: ExitParse},
+
+		{name: "version", args: []string{"version"}, code: ExitOK, stdout: "mockay "},
+		{name: "version arguments", args: []string{"version", "v1"}, code: ExitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := Run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("exit code %d, want %d\nstderr:\n%s", code, tt.code, stderr.String())
+			}
+			switch {
+			case code == ExitOK && !strings.Contains(stdout.String(), tt.stdout):
+				t.Errorf("stdout does not contain %q:\n%s", tt.stdout, stdout.String())
+			case code != ExitOK && stdout.Len() > 0:
+				t.Errorf("wrote to stdout on failure:\n%s", stdout.String())
+			case code != ExitOK && stderr.Len() == 0:
+				t.Error("no error was written to stderr")
+			case code != ExitOK && !strings.Contains(stderr.String(), tt.stderr):
+				t.Errorf("stderr does not contain %q:\n%s", tt.stderr, stderr.String())
+			}
+		})
+	}
+
+	// gen -o generates the mock for the package of the output file
+	src, err := os.ReadFile(filepath.Join(dir, "store", "store_mock.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "package store\n") {
+		t.Errorf("the mock written to the package of the interface is not in it:\n%s", src)
+	}
+}
+
+func TestExitCodeWrapped(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "not found", err: &mockgen.NotFoundError{Name: "Store"}, code: ExitNotFound},
+		{name: "not found with candidates", err: &mockgen.NotFoundError{Candidates: []mockgen.Candidate{{Name: "Store"}}}, code: ExitNotFound},
+		{name: "invalid position", err: fmt.Errorf("%w: line 9 is outside of the file", mockgen.ErrInvalidPosition), code: ExitNotFound},
+		{name: "parse", err: &mockgen.ParseError{Msg: "expected ';'"}, code: ExitParse},
+		{name: "unsupported", err: &mockgen.UnsupportedTypeError{Name: "Number"}, code: ExitUnsupported},
+		{name: "unexported", err: &mockgen.UnexportedError{Interface: "Store"}, code: ExitUnsupported},
+		{name: "path", err: &fs.PathError{Op: "open", Path: "store.go", Err: fs.ErrNotExist}, code: ExitIO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, err := range []error{
+				tt.err,
+				fmt.Errorf("could not generate the mock of Store: %w", tt.err),
+				errors.Join(errors.New("other error"), fmt.Errorf("store: %w", tt.err)),
+			} {
+				if code := exitCode(err); code != tt.code {
+					t.Errorf("exit code %d for %q, want %d", code, err, tt.code)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyBlocksWrite(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":         "module example.com/m\n",
+		"store/store.go": "package store\n\ntype Store interface {\n\tGet() int\n}\n",
+		"fake.tmpl":      "package mock\n\ntype Fake{{.Name}} struct{}\n\nfunc (Fake{{.Name}}) Get() int { return \"zero\" }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpl := filepath.Join(dir, "fake.tmpl")
+	output := filepath.Join(dir, "store_mock.go")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "gen", args: []string{"gen", "-verify", "-template", tmpl, "-name", "Store", "-o", output, filepath.Join(dir, "store", "store.go")}},
+		{name: "gen patterns", args: []string{"gen", "-verify", "-template", tmpl, filepath.Join(dir, "...")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := Run(tt.args, strings.NewReader(""), &stdout, &stderr)
+			if code != ExitError {
+				t.Fatalf("exit code %d, want %d\nstderr:\n%s", code, ExitError, stderr.String())
+			}
+			if want := "does not type check"; !strings.Contains(stderr.String(), want) {
+				t.Errorf("stderr does not contain %q:\n%s", want, stderr.String())
+			}
+			err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && strings.HasSuffix(path, ".go") && path != filepath.Join(dir, "store", "store.go") {
+					t.Errorf("the mock that does not type check was written to %s", path)
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
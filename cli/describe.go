@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"text/template"
+
+	"github.com/lindell/mockay/mockgen"
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// describeText is the text format of describe
+var describeText = template.Must(template.New("describe").Funcs(mockgen.TemplateFuncs()).Funcs(template.FuncMap{
+	"relative": relative,
+}).Parse(
+	`{{range .}}{{.Package.Name}}.{{.Name}}
+{{- if .TypeParams}}[{{range $i, $p := .TypeParams}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type.Expr}}{{end}}]{{end}}
+{{- with .Position}} {{relative .Filename}}:{{.Line}}:{{.Column}}{{end}}
+{{range .Methods}}	{{.Name}}({{argList .}}){{with resultList .}} {{.}}{{end}}
+{{end}}{{end}}`))
+
+// runDescribe prints the interfaces of a file, or the one at a position or
+// with a name
+func runDescribe(e *env, args []string) error {
+	flags := newFlagSet(e, "describe")
+	format := flags.String("format", "text", "the output format, text or json")
+	logFlags := addLogFlags(flags)
+	positionFlags := addPositionFlags(flags, "the interface, or a usage of it")
+	name := flags.String("name", "", "the name of the interface, declared in the package of the file")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return usagef("expected exactly one path, got %d", flags.NArg())
+	}
+	if err := oneOf("format", *format, "text", "json"); err != nil {
+		return err
+	}
+	pos, err := positionFlags.position()
+	if err != nil {
+		return err
+	}
+	if pos != nil && *name != "" {
+		return usagef("-pos and -name can not be used together")
+	}
+
+	logger, err := logFlags.logger(e.stderr)
+	if err != nil {
+		return err
+	}
+	generator := mockgen.New(mockgen.WithLogger(logger))
+	ifaces, err := generator.Describe(context.Background(), mockgen.Request{
+		Path:     flags.Arg(0),
+		Position: pos,
+		Name:     *name,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		if ifaces == nil {
+			ifaces = []*model.Interface{}
+		}
+		return writeJSON(e.stdout, ifaces)
+	}
+	return describeText.Execute(e.stdout, ifaces)
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// runGen generates a mock, or the mocks of packages when given patterns
+func runGen(e *env, args []string) error {
+	flags := newFlagSet(e, "gen")
+	logFlags := addLogFlags(flags)
+	outputFile := flags.String("o", "", "output file (otherwise stdout is used)")
+	positionFlags := addPositionFlags(flags, "the interface to be mocked, or a usage of it")
+	name := flags.String("name", "", "the name of the interface to be mocked, declared in the package of the file, instead of -pos")
+	inPlace := flags.Bool("inplace", false, "update the mock inside the file given by -o, keeping all other declarations in it")
+	modified := flags.Bool("modified", false, "read an archive of modified files from stdin, to be used instead of the files on disk")
+	stdinPath := flags.String("stdin-path", "", "the path of the file that the source read from stdin is the content of, which decides its package")
+	renderFlags := addRenderFlags(flags)
+	layoutFlags := addLayoutFlags(flags)
+	batchFlags := addBatchFlags(flags, "with patterns, ")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return usagef("expected a path or package patterns")
+	}
+	path := flags.Arg(0)
+
+	pos, err := positionFlags.position()
+	if err != nil {
+		return err
+	}
+	logger, err := logFlags.logger(e.stderr)
+	if err != nil {
+		return err
+	}
+	options, err := renderFlags.options(flags)
+	if err != nil {
+		return err
+	}
+	layout, err := layoutFlags.option(flags)
+	if err != nil {
+		return err
+	}
+	options = append(options, layout, mockgen.WithLogger(logger))
+
+	if flags.NArg() > 1 || isPattern(path) {
+		switch {
+		case pos != nil || *name != "":
+			return usagef("-pos and -name can not be used with package patterns")
+		case *outputFile != "" || *inPlace:
+			return usagef("-o and -inplace can not be used with package patterns, the mocks are written next to the packages")
+		case *modified:
+			return usagef("-modified can not be used with package patterns")
+		case *stdinPath != "":
+			return usagef("-stdin-path can not be used with package patterns")
+		}
+		generator := mockgen.New(append(options, batchFlags.options()...)...)
+		if *batchFlags.check {
+			return checkPackages(e, "gen", generator.Check, flags.Args())
+		}
+		return genPackages(e, generator.GenerateAll, generator, flags.Args())
+	}
+	if *batchFlags.check {
+		return usagef("-check can only be used with package patterns")
+	}
+
+	switch {
+	case pos == nil && *name == "":
+		return usagef("the interface must be selected with -pos or -name")
+	case pos != nil && *name != "":
+		return usagef("-pos and -name can not be used together")
+	case path == "-" && *modified:
+		return usagef("can not read both the source and modified files from stdin")
+	case path == "-" && *stdinPath == "":
+		// The package of the source, which the mock imports, is unknown
+		return usagef("the source read from stdin requires -stdin-path to name the file it belongs to")
+	case path != "-" && *stdinPath != "":
+		return usagef("-stdin-path can only be used when the source is read from stdin")
+	case *inPlace && *outputFile == "":
+		return usagef("-inplace requires an output file to be set with -o")
+	}
+
+	var out bytes.Buffer
+	options = append(options, mockgen.WithWriter(&out))
+	if pos != nil {
+		options = append(options, mockgen.WithPosition(*pos))
+	}
+	if *name != "" {
+		options = append(options, mockgen.WithName(*name))
+	}
+	if path == "-" {
+		src, err := io.ReadAll(e.stdin)
+		if err != nil {
+			return fmt.Errorf("could not read stdin: %w", err)
+		}
+		path = *stdinPath
+		options = append(options, mockgen.WithOverlay(map[string][]byte{path: src}))
+	}
+	if *modified {
+		files, err := mockgen.ParseArchive(e.stdin)
+		if err != nil {
+			return err
+		}
+		options = append(options, mockgen.WithOverlay(files))
+	}
+	if *inPlace {
+		options = append(options, mockgen.WithInPlace(*outputFile))
+	} else if *outputFile != "" {
+		options = append(options, mockgen.WithOutput(*outputFile))
+	}
+
+	generator := mockgen.New(options...)
+	if err := generator.Generate(path); err != nil {
+		return err
+	}
+
+	// The output file is only written once the mock has been generated, to
+	// not leave it truncated when generation fails
+	if *outputFile != "" && !*inPlace {
+		return os.WriteFile(*outputFile, out.Bytes(), 0660)
+	}
+	_, err = e.stdout.Write(out.Bytes())
+	return err
+}
+
+// isGenArg reports if the first argument of mockay, when it is not a
+// command, is meant for gen: a flag, a Go file, - for stdin, a directory or
+// a pattern with a / or ...
+func isGenArg(arg string) bool {
+	if strings.HasPrefix(arg, "-") || strings.HasSuffix(arg, ".go") || strings.Contains(arg, "/") || strings.Contains(arg, "...") {
+		return true
+	}
+	info, err := os.Stat(arg)
+	return err == nil && info.IsDir()
+}
+
+// isPattern reports if the argument of gen is a package pattern, like a
+// directory, an import path or either followed by /..., instead of a file
+func isPattern(arg string) bool {
+	if arg == "-" {
+		return false
+	}
+	if info, err := os.Stat(arg); err == nil {
+		return info.IsDir()
+	}
+	return !strings.HasSuffix(arg, ".go")
+}
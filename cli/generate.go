@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"github.com/lindell/mockay/mockgen"
+)
+
+// runGenerate generates the mocks of the interfaces marked with
+// //mockay:generate comments
+func runGenerate(e *env, args []string) error {
+	flags := newFlagSet(e, "generate")
+	logFlags := addLogFlags(flags)
+	renderFlags := addRenderFlags(flags)
+	layoutFlags := addLayoutFlags(flags)
+	batchFlags := addBatchFlags(flags, "")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	logger, err := logFlags.logger(e.stderr)
+	if err != nil {
+		return err
+	}
+	options, err := renderFlags.options(flags)
+	if err != nil {
+		return err
+	}
+	layout, err := layoutFlags.option(flags)
+	if err != nil {
+		return err
+	}
+	options = append(options, layout, mockgen.WithLogger(logger))
+	generator := mockgen.New(append(options, batchFlags.options()...)...)
+
+	if *batchFlags.check {
+		return checkPackages(e, "generate", generator.CheckAnnotated, patterns)
+	}
+	return genPackages(e, generator.GenerateAnnotated, generator, patterns)
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// layoutFlags decide where mocks are written to
+type layoutFlags struct {
+	layout   *string
+	outdir   *string
+	filename *string
+}
+
+func addLayoutFlags(flags *flag.FlagSet) layoutFlags {
+	return layoutFlags{
+		layout:   flags.String("layout", "mock", "the package of the mocks, mock (a package of their own in -outdir), test (the package of the interface, in _test.go files) or xtest (its external test package)"),
+		outdir:   flags.String("outdir", "mock", "the directory of the mocks with -layout mock, relative to the package of the interface"),
+		filename: flags.String("filename", "", "the template of the file names of the mocks, e.g. {{.Interface | snake}}_mock.go or mock_{{.Package}}.go (default {{.Interface | snake}}.go, or {{.Interface | snake}}_mock_test.go in test packages)"),
+	}
+}
+
+// option returns the option setting the layout
+func (l layoutFlags) option(flags *flag.FlagSet) (mockgen.Option, error) {
+	if err := oneOf("layout", *l.layout, "mock", "test", "xtest"); err != nil {
+		return nil, err
+	}
+	layout := mockgen.Layout{Dir: *l.outdir}
+	switch *l.layout {
+	case "test":
+		layout.Package = mockgen.TestPackage
+	case "xtest":
+		layout.Package = mockgen.ExternalTestPackage
+	}
+	outdirSet := false
+	flags.Visit(func(f *flag.Flag) { outdirSet = outdirSet || f.Name == "outdir" })
+	if outdirSet && layout.Package != mockgen.MockPackage {
+		return nil, usagef("-outdir can only be used with -layout mock, mocks in test packages are written next to the interface")
+	}
+
+	if *l.filename != "" {
+		tmpl, err := mockgen.ParseFileName(*l.filename)
+		if err != nil {
+			return nil, usagef("invalid -filename: %s", err)
+		}
+		layout.FileName = tmpl
+	}
+	return mockgen.WithLayout(layout), nil
+}
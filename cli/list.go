@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// runList prints the interfaces of a file or directory, the current directory
+// if no path is given
+func runList(e *env, args []string) error {
+	flags := newFlagSet(e, "list")
+	format := flags.String("format", "text", "the output format, text or json")
+	logFlags := addLogFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return usagef("expected at most one path, got %d", flags.NArg())
+	}
+	if err := oneOf("format", *format, "text", "json"); err != nil {
+		return err
+	}
+	path := "."
+	if flags.NArg() == 1 {
+		path = flags.Arg(0)
+	}
+
+	logger, err := logFlags.logger(e.stderr)
+	if err != nil {
+		return err
+	}
+	generator := mockgen.New(mockgen.WithLogger(logger))
+	ifaces, err := generator.List(context.Background(), path)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		if ifaces == nil {
+			ifaces = []mockgen.ListedInterface{}
+		}
+		return writeJSON(e.stdout, ifaces)
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	for _, iface := range ifaces {
+		methods, exported, mock := "methods", "unexported", "no mock"
+		if iface.Methods == 1 {
+			methods = "method"
+		}
+		if iface.Exported {
+			exported = "exported"
+		}
+		if iface.Mock != "" {
+			mock = "mock in " + relative(iface.Mock)
+		}
+		fmt.Fprintf(w, "%s:%d:%d\t%s\t%d %s\t%s\t%s\n",
+			relative(iface.Position.Filename), iface.Position.Line, iface.Position.Column,
+			iface.Name, iface.Methods, methods, exported, mock)
+	}
+	return w.Flush()
+}
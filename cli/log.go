@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// logFlags are the flags controlling what is logged to stderr
+type logFlags struct {
+	verbose *bool
+	level   *string
+}
+
+func addLogFlags(flags *flag.FlagSet) logFlags {
+	return logFlags{
+		verbose: flags.Bool("verbose", false, "log what is done to stderr, the same as -log-level info"),
+		level:   flags.String("log-level", "", "the lowest level logged to stderr, debug, info or warn (default warn)"),
+	}
+}
+
+// logger creates a logger writing to w at the level of the flags
+func (l logFlags) logger(w io.Writer) (mockgen.Logger, error) {
+	level := *l.level
+	if level == "" {
+		level = "warn"
+		if *l.verbose {
+			level = "info"
+		}
+	}
+	if err := oneOf("log-level", level, "debug", "info", "warn"); err != nil {
+		return nil, err
+	}
+	var slogLevel slog.Level
+	if err := slogLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+
+	return mockgen.NewSlogLogger(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: slogLevel,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// The time only adds noise to the output of a short lived command
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})), nil
+}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"github.com/lindell/mockay/lsp"
+)
+
+// runLSP runs the language server on stdin and stdout
+func runLSP(e *env, args []string) error {
+	flags := newFlagSet(e, "lsp")
+	logFlags := addLogFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return usagef("lsp does not take any arguments")
+	}
+
+	logger, err := logFlags.logger(e.stderr)
+	if err != nil {
+		return err
+	}
+	return lsp.Serve(e.stdin, e.stdout, logger)
+}
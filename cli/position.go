@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+var position = regexp.MustCompile(`^(?:(\d+):(\d+)|#(\d+))$`)
+
+// positionFlags are the flags used to select an interface by position
+type positionFlags struct {
+	pos      *string
+	encoding *string
+}
+
+func addPositionFlags(flags *flag.FlagSet, what string) positionFlags {
+	return positionFlags{
+		pos:      flags.String("pos", "", "the position of "+what+", as line:column or #offset in bytes"),
+		encoding: flags.String("pos-encoding", "utf8", "how the column of -pos is counted, utf8 (bytes) or utf16 (code units, as used by LSP)"),
+	}
+}
+
+// position parses the position flags, the position is nil if -pos is not set
+func (p positionFlags) position() (*mockgen.Position, error) {
+	if err := oneOf("pos-encoding", *p.encoding, "utf8", "utf16"); err != nil {
+		return nil, err
+	}
+	if *p.pos == "" {
+		return nil, nil
+	}
+	pos := mockgen.Position{}
+	if *p.encoding == "utf16" {
+		pos.Encoding = mockgen.UTF16
+	}
+	match := position.FindStringSubmatch(*p.pos)
+	if match == nil {
+		return nil, usagef("invalid -pos %q, expected line:column or #offset", *p.pos)
+	}
+	var err error
+	if match[3] != "" {
+		pos.Offset, err = strconv.Atoi(match[3])
+	} else if pos.X, err = strconv.Atoi(match[1]); err == nil {
+		pos.Y, err = strconv.Atoi(match[2])
+	}
+	if err != nil {
+		return nil, usagef("invalid -pos %q: %s", *p.pos, err)
+	}
+	if match[3] == "" && (pos.X == 0 || pos.Y == 0) {
+		return nil, usagef("invalid -pos %q, lines and columns start at 1", *p.pos)
+	}
+	return &pos, nil
+}
+
+// relative returns path relative to the working directory if it is inside it
+func relative(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
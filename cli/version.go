@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Version is the version of mockay, set when building a release with
+// -ldflags "-X github.com/lindell/mockay/cli.Version=v1.2.3"
+var Version = ""
+
+// version returns Version, or the version of the module when installed with
+// go install
+func version() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// runVersion prints the version
+func runVersion(e *env, args []string) error {
+	flags := newFlagSet(e, "version")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return usagef("version does not take any arguments")
+	}
+	_, err := fmt.Fprintf(e.stdout, "mockay %s\n", version())
+	return err
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// runWatch regenerates mocks when the interfaces they mock change, until
+// interrupted
+func runWatch(e *env, args []string) error {
+	flags := newFlagSet(e, "watch")
+	logFlags := addLogFlags(flags)
+	interval := flags.Duration("interval", time.Second, "how often the files are checked for changes")
+	renderFlags := addRenderFlags(flags)
+	layoutFlags := addLayoutFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	options, err := renderFlags.options(flags)
+	if err != nil {
+		return err
+	}
+	layout, err := layoutFlags.option(flags)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return usagef("-interval must be positive")
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	logger, err := logFlags.logger(e.stderr)
+	if err != nil {
+		return err
+	}
+	options = append(options, layout, mockgen.WithLogger(logger))
+	generator := mockgen.New(options...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return generator.Watch(ctx, patterns, *interval, func(event mockgen.WatchEvent) {
+		switch {
+		case event.Err == nil:
+			fmt.Fprintf(e.stdout, "regenerated %s (%s)\n", relative(event.Path), event.Interface)
+		case event.Interface != "":
+			fmt.Fprintf(e.stderr, "failed %s (%s): %s\n", relative(event.Path), event.Interface, event.Err)
+		default:
+			fmt.Fprintf(e.stderr, "error: %s\n", event.Err)
+		}
+	})
+}
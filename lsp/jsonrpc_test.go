@@ -0,0 +1,43 @@
+package lsp
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConnRead(t *testing.T) {
+	c := newConn(strings.NewReader("Content-Length: 40\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"initialized\"}"), io.Discard)
+	req, err := c.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "initialized" {
+		t.Errorf("got method %q", req.Method)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{name: "huge length", header: "Content-Length: 9223372036854775807", want: io.ErrUnexpectedEOF},
+		{name: "truncated", header: "Content-Length: 100", want: io.ErrUnexpectedEOF},
+		{name: "negative length", header: "Content-Length: -1"},
+		{name: "invalid length", header: "Content-Length: ten"},
+		{name: "missing length", header: "Content-Type: application/json"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newConn(strings.NewReader(test.header+"\r\n\r\n{}"), io.Discard)
+			_, err := c.read()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if test.want != nil && !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
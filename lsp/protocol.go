@@ -0,0 +1,126 @@
+package lsp
+
+// The subset of the Language Server Protocol used by mockay
+
+type initializeParams struct {
+	Capabilities clientCapabilities `json:"capabilities"`
+}
+
+type clientCapabilities struct {
+	TextDocument struct {
+		CodeAction struct {
+			ResolveSupport *struct {
+				Properties []string `json:"properties"`
+			} `json:"resolveSupport"`
+		} `json:"codeAction"`
+	} `json:"textDocument"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync   int                `json:"textDocumentSync"`
+	CodeActionProvider codeActionProvider `json:"codeActionProvider"`
+}
+
+type codeActionProvider struct {
+	CodeActionKinds []string `json:"codeActionKinds"`
+	ResolveProvider bool     `json:"resolveProvider"`
+}
+
+type serverInfo struct {
+	Name string `json:"name"`
+}
+
+// textDocumentSyncFull makes the client send the full content on every change
+const textDocumentSyncFull = 1
+
+// codeActionKindRefactor is the kind of the offered code actions, shown by
+// editors among the actions available at the cursor
+const codeActionKindRefactor = "refactor"
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type versionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+type textDocumentItem struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   versionedTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type codeActionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Range        lspRange               `json:"range"`
+}
+
+type codeAction struct {
+	Title string         `json:"title"`
+	Kind  string         `json:"kind"`
+	Edit  *workspaceEdit `json:"edit,omitempty"`
+	// Data is kept by the client and sent back when resolving the action
+	Data *codeActionData `json:"data,omitempty"`
+}
+
+// codeActionData is where the mock of a code action is generated from
+type codeActionData struct {
+	URI      string   `json:"uri"`
+	Position position `json:"position"`
+}
+
+type workspaceEdit struct {
+	DocumentChanges []interface{} `json:"documentChanges"`
+}
+
+type createFile struct {
+	Kind    string             `json:"kind"`
+	URI     string             `json:"uri"`
+	Options *createFileOptions `json:"options,omitempty"`
+}
+
+type createFileOptions struct {
+	IgnoreIfExists bool `json:"ignoreIfExists"`
+}
+
+type textDocumentEdit struct {
+	TextDocument versionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []textEdit                      `json:"edits"`
+}
+
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
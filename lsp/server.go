@@ -0,0 +1,353 @@
+// Package lsp implements a language server that offers code actions to generate mocks
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// ErrExitWithoutShutdown is returned by Serve when the client exits without shutting down the server first
+var ErrExitWithoutShutdown = errors.New("exit without shutdown")
+
+// server keeps the state of a language server session
+type server struct {
+	conn   *conn
+	logger mockgen.Logger
+	docs   map[string][]byte
+	// generator is kept for the session so that files are only parsed again
+	// when they change, the open documents are its overlay. Files that are
+	// not open are only read once.
+	generator *mockgen.Generator
+	// resolveEdits is set if the client resolves the edits of code actions
+	// when they are chosen, instead of needing them when they are offered
+	resolveEdits bool
+	initialized  bool
+	shutdown     bool
+}
+
+// Serve runs a language server that reads from r and writes to w, until the
+// client sends the exit notification or r is closed
+func Serve(r io.Reader, w io.Writer, logger mockgen.Logger) error {
+	s := &server{
+		conn:      newConn(r, w),
+		logger:    logger,
+		docs:      map[string][]byte{},
+		generator: mockgen.New(mockgen.WithLogger(logger)),
+	}
+	for {
+		req, err := s.conn.read()
+		var rpcErr *responseError
+		if errors.As(err, &rpcErr) {
+			if err := s.conn.replyError(nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		s.logger.Debug("received message", "method", req.Method)
+		result, rpcErr := s.handle(req)
+		if req.ID == nil {
+			if rpcErr != nil {
+				s.logger.Warn("notification failed", "method", req.Method, "err", rpcErr.Message)
+			}
+			continue
+		}
+		if rpcErr != nil {
+			err = s.conn.replyError(req.ID, rpcErr)
+		} else {
+			err = s.conn.reply(req.ID, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(req *request) (interface{}, *responseError) {
+	if !s.initialized && req.Method != "initialize" {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server is not initialized"}
+	}
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		var params initializeParams
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return nil, invalidParams(err)
+			}
+		}
+		if support := params.Capabilities.TextDocument.CodeAction.ResolveSupport; support != nil {
+			for _, property := range support.Properties {
+				s.resolveEdits = s.resolveEdits || property == "edit"
+			}
+		}
+		s.initialized = true
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync: textDocumentSyncFull,
+				CodeActionProvider: codeActionProvider{
+					CodeActionKinds: []string{codeActionKindRefactor},
+					ResolveProvider: true,
+				},
+			},
+			ServerInfo: serverInfo{Name: "mockay"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return nil, s.setDocument(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		last := params.ContentChanges[len(params.ContentChanges)-1]
+		return nil, s.setDocument(params.TextDocument.URI, last.Text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if path, err := uriToPath(params.TextDocument.URI); err == nil {
+			delete(s.docs, path)
+			s.generator.SetOverlay(path, nil)
+		}
+		return nil, nil
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.codeActions(params)
+	case "codeAction/resolve":
+		var action codeAction
+		if err := json.Unmarshal(req.Params, &action); err != nil {
+			return nil, invalidParams(err)
+		}
+		if action.Data == nil {
+			return nil, invalidParams(errors.New("the code action has no data"))
+		}
+		if err := s.resolve(&action); err != nil {
+			return nil, &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		return action, nil
+	}
+
+	if req.ID == nil || strings.HasPrefix(req.Method, "$/") {
+		// Notifications that are not handled are ignored
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+func (s *server) setDocument(uri, text string) *responseError {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return invalidParams(err)
+	}
+	s.docs[path] = []byte(text)
+	s.generator.SetOverlay(path, s.docs[path])
+	return nil
+}
+
+// codeActions offers to generate a mock when the start of the range is on an
+// interface, or on a usage of one. The mock is only generated when the action
+// is resolved, unless the client can not resolve actions.
+func (s *server) codeActions(params codeActionParams) (interface{}, *responseError) {
+	actions := []codeAction{}
+
+	path, err := uriToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	if !strings.HasSuffix(path, ".go") {
+		return actions, nil
+	}
+
+	ifaces, err := s.generator.Describe(context.Background(), mockgen.Request{
+		Path:     path,
+		Position: mockPosition(params.Range.Start),
+	})
+	if errors.Is(err, mockgen.ErrInterfaceNotFound) || errors.Is(err, mockgen.ErrInvalidPosition) {
+		// Code actions are requested for any position, most have nothing to mock
+		return actions, nil
+	}
+	if err != nil {
+		s.logger.Warn("could not find interface", "file", path, "err", err)
+		return actions, nil
+	}
+
+	action := codeAction{
+		Title: "Generate mock for " + ifaces[0].Name,
+		Kind:  codeActionKindRefactor,
+		Data: &codeActionData{
+			URI:      params.TextDocument.URI,
+			Position: params.Range.Start,
+		},
+	}
+	if !s.resolveEdits {
+		if err := s.resolve(&action); err != nil {
+			s.logger.Warn("could not generate mock", "file", path, "err", err)
+			return actions, nil
+		}
+	}
+	return append(actions, action), nil
+}
+
+// resolve generates the mock of a code action and sets the edit that writes it
+func (s *server) resolve(action *codeAction) error {
+	path, err := uriToPath(action.Data.URI)
+	if err != nil {
+		return err
+	}
+	files, err := s.generator.GenerateFiles(context.Background(), mockgen.Request{
+		Path:     path,
+		Position: mockPosition(action.Data.Position),
+		Merge:    true,
+	})
+	if err != nil {
+		return err
+	}
+
+	action.Edit = &workspaceEdit{}
+	for _, file := range files {
+		edit, err := s.replaceFile(file.Path, string(file.Source))
+		if err != nil {
+			return err
+		}
+		action.Edit.DocumentChanges = append(action.Edit.DocumentChanges, edit.DocumentChanges...)
+	}
+	return nil
+}
+
+// mockPosition converts a position in a document to a position of mockgen
+func mockPosition(pos position) *mockgen.Position {
+	return &mockgen.Position{
+		X:        pos.Line + 1,
+		Y:        pos.Character + 1,
+		Encoding: mockgen.UTF16,
+	}
+}
+
+// replaceFile creates an edit that sets the content of the file at path,
+// creating the file if it does not exist
+func (s *server) replaceFile(path, content string) (*workspaceEdit, error) {
+	uri := pathToURI(path)
+
+	current, ok := s.docs[path]
+	if !ok {
+		var err error
+		current, err = os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return &workspaceEdit{
+				DocumentChanges: []interface{}{
+					createFile{
+						Kind:    "create",
+						URI:     uri,
+						Options: &createFileOptions{IgnoreIfExists: true},
+					},
+					textDocumentEdit{
+						TextDocument: versionedTextDocumentIdentifier{URI: uri},
+						Edits: []textEdit{
+							{NewText: content},
+						},
+					},
+				},
+			}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &workspaceEdit{
+		DocumentChanges: []interface{}{
+			textDocumentEdit{
+				TextDocument: versionedTextDocumentIdentifier{URI: uri},
+				Edits: []textEdit{
+					{
+						Range:   lspRange{End: endPosition(current)},
+						NewText: content,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// endPosition returns the position of the end of the content, with the
+// character counted in UTF-16 code units
+func endPosition(content []byte) position {
+	line := bytes.Count(content, []byte("\n"))
+	last := content[bytes.LastIndexByte(content, '\n')+1:]
+	return position{
+		Line:      line,
+		Character: len(utf16.Encode([]rune(string(last)))),
+	}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported uri scheme: %s", u.Scheme)
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		// file:///C:/dir has the path /C:/dir
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path), nil
+}
+
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
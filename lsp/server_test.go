@@ -0,0 +1,219 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lindell/mockay/mockgen"
+)
+
+// testClient sends requests to a server started with Serve and reads its responses
+type testClient struct {
+	t      *testing.T
+	conn   *conn
+	reader *bufio.Reader
+	id     int
+	// done is closed when Serve returns err
+	done chan struct{}
+	err  error
+}
+
+func startServer(t *testing.T) *testClient {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &testClient{
+		t:      t,
+		conn:   newConn(nil, clientOut),
+		reader: bufio.NewReader(clientIn),
+		done:   make(chan struct{}),
+	}
+	logger := mockgen.NewSlogLogger(slog.NewTextHandler(io.Discard, nil))
+	go func() {
+		c.err = Serve(serverIn, serverOut, logger)
+		serverOut.Close()
+		close(c.done)
+	}()
+	t.Cleanup(func() {
+		clientOut.Close()
+		<-c.done
+	})
+	return c
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	c.t.Helper()
+	if err := c.conn.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and decodes the result of its response into result
+func (c *testClient) call(method string, params, result interface{}) {
+	c.t.Helper()
+	c.id++
+	err := c.conn.write(map[string]interface{}{"jsonrpc": "2.0", "id": c.id, "method": method, "params": params})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	header, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, content); err != nil {
+		c.t.Fatal(err)
+	}
+	var resp struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *responseError  `json:"error"`
+	}
+	if err := json.Unmarshal(content, &resp); err != nil {
+		c.t.Fatal(err)
+	}
+	if resp.ID != c.id {
+		c.t.Fatalf("got response to %d, want %d", resp.ID, c.id)
+	}
+	if resp.Error != nil {
+		c.t.Fatalf("%s failed: %s", method, resp.Error.Message)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// testAction is a code action as decoded by a client
+type testAction struct {
+	Title string          `json:"title"`
+	Data  json.RawMessage `json:"data"`
+	Edit  *struct {
+		DocumentChanges []struct {
+			Kind  string `json:"kind"`
+			URI   string `json:"uri"`
+			Edits []struct {
+				NewText string `json:"newText"`
+			} `json:"edits"`
+		} `json:"documentChanges"`
+	} `json:"edit"`
+}
+
+// newText returns the text the edit of the action writes
+func (a testAction) newText() string {
+	var text strings.Builder
+	if a.Edit != nil {
+		for _, change := range a.Edit.DocumentChanges {
+			for _, edit := range change.Edits {
+				text.WriteString(edit.NewText)
+			}
+		}
+	}
+	return text.String()
+}
+
+func TestServerCodeAction(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.go")
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := "package store\n\ntype Store interface {\n\tGet(key string) string\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(path)
+	codeActionParams := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"range": map[string]interface{}{
+			"start": map[string]int{"line": 2, "character": 6},
+			"end":   map[string]int{"line": 2, "character": 6},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		capabilities string
+	}{
+		{name: "resolved", capabilities: `{"textDocument":{"codeAction":{"resolveSupport":{"properties":["edit"]}}}}`},
+		{name: "without resolve", capabilities: `{}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := startServer(t)
+			var result initializeResult
+			c.call("initialize", map[string]interface{}{"capabilities": json.RawMessage(test.capabilities)}, &result)
+			if !result.Capabilities.CodeActionProvider.ResolveProvider {
+				t.Error("the server does not resolve code actions")
+			}
+			c.notify("initialized", struct{}{})
+			c.notify("textDocument/didOpen", map[string]interface{}{
+				"textDocument": map[string]interface{}{"uri": uri, "version": 1, "text": src},
+			})
+
+			// Every action is generated from the latest content of the document
+			for _, method := range []string{"Get", "Put"} {
+				if method == "Put" {
+					changed := strings.Replace(src, "\tGet", "\tPut(key, value string)\n\tGet", 1)
+					c.notify("textDocument/didChange", map[string]interface{}{
+						"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+						"contentChanges": []map[string]string{{"text": changed}},
+					})
+				}
+
+				var actions []testAction
+				c.call("textDocument/codeAction", codeActionParams, &actions)
+				if len(actions) != 1 {
+					t.Fatalf("got %d actions, want 1", len(actions))
+				}
+				action := actions[0]
+				if action.Title != "Generate mock for Store" {
+					t.Errorf("got title %q", action.Title)
+				}
+				if test.name == "resolved" {
+					if action.Edit != nil {
+						t.Error("the edit is generated before the action is resolved")
+					}
+					c.call("codeAction/resolve", action, &action)
+				}
+
+				text := action.newText()
+				want := fmt.Sprintf("func (m *Mocked) %s(", method)
+				if !strings.Contains(text, want) {
+					t.Errorf("the edit does not contain %q:\n%s", want, text)
+				}
+			}
+
+			var actions []testAction
+			params := map[string]interface{}{
+				"textDocument": map[string]string{"uri": uri},
+				"range":        map[string]interface{}{"start": map[string]int{"line": 0, "character": 0}, "end": map[string]int{"line": 0, "character": 0}},
+			}
+			c.call("textDocument/codeAction", params, &actions)
+			if len(actions) != 0 {
+				t.Errorf("got %d actions on the package clause, want none", len(actions))
+			}
+
+			var shutdown interface{}
+			c.call("shutdown", nil, &shutdown)
+			c.notify("exit", nil)
+			<-c.done
+			if c.err != nil {
+				t.Errorf("serve: %v", c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package mockgen
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// generateDirective is the comment that marks an interface to be mocked by
+// GenerateAnnotated
+const generateDirective = "//mockay:generate"
+
+// Annotation are the settings of an interface marked to be mocked with a
+// comment in its doc, e.g.
+//
+//	//mockay:generate name=MockStore out=store_mock_test.go style=spy
+type Annotation struct {
+	// Name is the name of the mock, the name of the interface if not set
+	Name string
+	// Out is the file the mock is written to, relative to the directory of
+	// the interface. The path given by the layout of the generator is used
+	// if not set.
+	Out string
+	// Style is the style of the mock, the style or template of the
+	// generator is used if not set
+	Style string
+}
+
+// GenerateAnnotated generates mocks of the interfaces marked with a
+// //mockay:generate comment in the packages matched by the patterns, with
+// the settings of the comments. Otherwise it works as GenerateAll.
+func (f *Generator) GenerateAnnotated(ctx context.Context, patterns []string) ([]GeneratedFile, error) {
+	return f.generatePlans(ctx, f.planRequests(ctx, patterns, "annotated", annotatedRequest))
+}
+
+// CheckAnnotated returns the files GenerateAnnotated would write, those that
+// are missing or out of date, as Check does
+func (f *Generator) CheckAnnotated(ctx context.Context, patterns []string) ([]GeneratedFile, error) {
+	return checkPlans(f.planRequests(ctx, patterns, "annotated", annotatedRequest))
+}
+
+// annotatedRequest requests a mock of the interface if it has an annotation
+func annotatedRequest(file *file, spec *ast.TypeSpec) (*Request, error) {
+	a, err := file.annotation(spec)
+	if a == nil || err != nil {
+		return nil, err
+	}
+	if _, ok := spec.Type.(*ast.InterfaceType); !ok {
+		return nil, fmt.Errorf("%s: %s can only be given for interfaces, %s is not one",
+			formatPosition(file.position(spec.Name.Pos())), generateDirective, spec.Name.Name)
+	}
+	req := &Request{
+		Path:     file.path,
+		Name:     spec.Name.Name,
+		MockName: a.Name,
+		Style:    a.Style,
+	}
+	if req.MockName == "" {
+		req.MockName = spec.Name.Name
+	}
+	if a.Out != "" {
+		req.Output = a.Out
+		if !filepath.IsAbs(req.Output) {
+			req.Output = filepath.Join(filepath.Dir(overlayKey(file.path)), req.Output)
+		}
+	}
+	return req, nil
+}
+
+// annotation returns the annotation in the doc of the type spec, it is nil if
+// there is none
+func (f *file) annotation(spec *ast.TypeSpec) (*Annotation, error) {
+	doc := f.typeSpecDoc(spec)
+	if doc == nil {
+		return nil, nil
+	}
+
+	var a *Annotation
+	for _, c := range doc.List {
+		if c.Text != generateDirective && !strings.HasPrefix(c.Text, generateDirective+" ") {
+			continue
+		}
+		pos := formatPosition(f.position(c.Pos()))
+		if a != nil {
+			return nil, fmt.Errorf("%s: %s is given twice for %s", pos, generateDirective, spec.Name.Name)
+		}
+		a = &Annotation{}
+		for _, setting := range strings.Fields(strings.TrimPrefix(c.Text, generateDirective)) {
+			key, value, ok := strings.Cut(setting, "=")
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			if !ok || value == "" {
+				return nil, fmt.Errorf("%s: invalid setting %q, expected key=value", pos, setting)
+			}
+			switch key {
+			case "name":
+				if !token.IsIdentifier(value) {
+					return nil, fmt.Errorf("%s: invalid name %q, it must be an identifier", pos, value)
+				}
+				a.Name = value
+			case "out":
+				if !strings.HasSuffix(value, ".go") {
+					return nil, fmt.Errorf("%s: invalid out %q, it must be a .go file", pos, value)
+				}
+				a.Out = value
+			case "style":
+				a.Style = value
+			default:
+				return nil, fmt.Errorf("%s: unknown setting %q, expected name, out or style", pos, key)
+			}
+		}
+	}
+	return a, nil
+}
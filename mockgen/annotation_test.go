@@ -0,0 +1,120 @@
+package mockgen
+
+import (
+	"context"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnnotation(t *testing.T) {
+	tests := []struct {
+		name string
+		// decl is the declaration of Store, with its doc
+		decl string
+		want *Annotation
+		// err is contained in the error, if parsing the annotation fails
+		err string
+	}{
+		{name: "none", decl: "// Store stores\ntype Store interface{}", want: nil},
+		{name: "empty", decl: "//mockay:generate\ntype Store interface{}", want: &Annotation{}},
+		{
+			name: "settings",
+			decl: "// Store stores\n//\n//mockay:generate name=MockStore out=store_mock_test.go style=spy\ntype Store interface{}",
+			want: &Annotation{Name: "MockStore", Out: "store_mock_test.go", Style: "spy"},
+		},
+		{name: "quoted", decl: "//mockay:generate out=\"mocks/store.go\"\ntype Store interface{}", want: &Annotation{Out: "mocks/store.go"}},
+		{name: "grouped", decl: "type (\n\t//mockay:generate name=Fake\n\tStore interface{}\n\tOther interface{}\n)", want: &Annotation{Name: "Fake"}},
+		{name: "other directive", decl: "//mockay:generated\ntype Store interface{}", want: nil},
+		{name: "not a directive", decl: "// mockay:generate\ntype Store interface{}", want: nil},
+
+		{name: "missing value", decl: "//mockay:generate name\ntype Store interface{}", err: `5:1: invalid setting "name", expected key=value`},
+		{name: "empty value", decl: "//mockay:generate name=\ntype Store interface{}", err: `invalid setting "name=", expected key=value`},
+		{name: "invalid name", decl: "//mockay:generate name=1Store\ntype Store interface{}", err: `invalid name "1Store"`},
+		{name: "invalid out", decl: "//mockay:generate out=store.txt\ntype Store interface{}", err: `invalid out "store.txt"`},
+		{name: "unknown setting", decl: "//mockay:generate color=red\ntype Store interface{}", err: `unknown setting "color"`},
+		{name: "twice", decl: "//mockay:generate\n//mockay:generate name=Fake\ntype Store interface{}", err: "6:1: //mockay:generate is given twice for Store"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := "package store\n\nvar _ = 1\n\n" + test.decl + "\n"
+			f, err := openFile(token.NewFileSet(), "store.go", map[string][]byte{overlayKey("store.go"): []byte(src)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := f.annotation(f.lookupType("Store"))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(a, test.want) {
+				t.Errorf("got %+v, want %+v", a, test.want)
+			}
+		})
+	}
+}
+
+func TestGenerateAnnotated(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"store/store.go": "package store\n\n" +
+			"//mockay:generate name=MockStore out=store_mock_test.go\n" +
+			"type Store interface {\n\tGet() int\n}\n\n" +
+			"// Unmarked is not mocked\ntype Unmarked interface {\n\tGet() int\n}\n\n" +
+			"//mockay:generate out=cache_mock_test.go\ntype cache interface {\n\tget() int\n}\n\n" +
+			"// Item is not an interface\ntype Item struct{}\n",
+	})
+
+	g := New(WithVerify())
+	files, err := g.GenerateAnnotated(context.Background(), []string{filepath.Join(dir, "...")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mocks []string
+	for _, file := range files {
+		mocks = append(mocks, filepath.Base(file.Path)+":"+strings.Join(file.Interfaces, ","))
+	}
+	if want := []string{"cache_mock_test.go:cache", "store_mock_test.go:Store"}; !reflect.DeepEqual(mocks, want) {
+		t.Fatalf("got mocks %q, want %q", mocks, want)
+	}
+	if src := string(files[1].Source); !strings.Contains(src, "package store\n") || !strings.Contains(src, "type MockStore struct") {
+		t.Errorf("the settings of the annotation are not used:\n%s", src)
+	}
+}
+
+func TestGenerateAnnotatedNonInterface(t *testing.T) {
+	tests := []struct {
+		name string
+		decl string
+	}{
+		{name: "struct", decl: "type Item struct{}"},
+		{name: "named interface", decl: "type Item Store"},
+		{name: "alias", decl: "type Item = Store"},
+		{name: "func", decl: "type Item func() int"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"go.mod":   "module example.com/m\n",
+				"store.go": "package store\n\ntype Store interface {\n\tGet() int\n}\n\n//mockay:generate\n" + test.decl + "\n",
+			})
+
+			g := New()
+			_, err := g.GenerateAnnotated(context.Background(), []string{dir})
+			want := filepath.Join(dir, "store.go") + ":8:6: //mockay:generate can only be given for interfaces, Item is not one"
+			if err == nil || err.Error() != want {
+				t.Errorf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
@@ -1,60 +1,116 @@
 package mockgen
 
 import (
-	"fmt"
 	"go/ast"
-
-	"github.com/lindell/mockay/astcopy"
+	"go/token"
+	"strings"
 )
 
 func interfaceName(spec *ast.TypeSpec) string {
 	return spec.Name.Name
 }
 
-func copyFieldList(list *ast.FieldList) *ast.FieldList {
-	cpList := make([]*ast.Field, len(list.List))
-	varI := 1
-	for i, f := range list.List {
-		var names []*ast.Ident
-		if len(f.Names) == 0 {
-			names = []*ast.Ident{
-				{
-					Name: fmt.Sprintf("var%d", varI),
-				},
-			}
-			varI++
-		} else {
-			names = astcopy.IdentList(f.Names)
-		}
+func comment(str string) *ast.CommentGroup {
+	return &ast.CommentGroup{
+		List: []*ast.Comment{
+			{
+				Text: str,
+			},
+		},
+	}
+}
 
-		cpList[i] = &ast.Field{
-			Type:  astcopy.Expr(f.Type),
-			Tag:   astcopy.BasicLit(f.Tag),
-			Names: names,
+// docComment creates a comment starting with the summary, followed by the
+// text of doc as a separate paragraph if there is any
+func docComment(summary string, doc string) *ast.CommentGroup {
+	group := comment("// " + summary)
+	if doc == "" {
+		return group
+	}
+	group.List = append(group.List, &ast.Comment{Text: "//"})
+	for _, line := range strings.Split(strings.TrimRight(doc, "\n"), "\n") {
+		switch {
+		case line == "":
+			line = "//"
+		case strings.HasPrefix(line, "\t"):
+			line = "//" + line
+		default:
+			line = "// " + line
 		}
+		group.List = append(group.List, &ast.Comment{Text: line})
 	}
+	return group
+}
 
-	return &ast.FieldList{
-		List: cpList,
+func ident(name string) *ast.Ident {
+	return &ast.Ident{
+		Name: name,
 	}
 }
 
-func argsFromParams(params *ast.FieldList) []ast.Expr {
-	var expr []ast.Expr
-	for _, f := range params.List {
-		for _, n := range f.Names {
-			expr = append(expr, astcopy.Ident(n))
-		}
+func selector(x ast.Expr, sel string) *ast.SelectorExpr {
+	return &ast.SelectorExpr{
+		X:   x,
+		Sel: ident(sel),
 	}
-	return expr
 }
 
-func comment(str string) *ast.CommentGroup {
-	return &ast.CommentGroup{
-		List: []*ast.Comment{
-			{
-				Text: str,
-			},
+func field(name string, typ ast.Expr) *ast.Field {
+	return &ast.Field{
+		Names: []*ast.Ident{ident(name)},
+		Type:  typ,
+	}
+}
+
+func call(fun ast.Expr, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun:  fun,
+		Args: args,
+	}
+}
+
+func callStmt(fun ast.Expr, args ...ast.Expr) ast.Stmt {
+	return &ast.ExprStmt{
+		X: call(fun, args...),
+	}
+}
+
+func assign(tok token.Token, lhs []ast.Expr, rhs ...ast.Expr) ast.Stmt {
+	return &ast.AssignStmt{
+		Lhs: lhs,
+		Tok: tok,
+		Rhs: rhs,
+	}
+}
+
+func returnStmt(results ...ast.Expr) ast.Stmt {
+	return &ast.ReturnStmt{
+		Results: results,
+	}
+}
+
+func ifStmt(cond ast.Expr, body ...ast.Stmt) ast.Stmt {
+	return &ast.IfStmt{
+		Cond: cond,
+		Body: &ast.BlockStmt{
+			List: body,
+		},
+	}
+}
+
+func structType(fields []*ast.Field) *ast.StructType {
+	return &ast.StructType{
+		Fields: &ast.FieldList{
+			List: fields,
 		},
 	}
 }
+
+func fieldList(fields []*ast.Field) *ast.FieldList {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ast.FieldList{
+		List: fields,
+	}
+}
@@ -0,0 +1,435 @@
+package mockgen
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GenerateAll generates mocks of the exported interfaces declared in the
+// packages matched by the patterns, with up to the number of workers set by
+// WithWorkers generating at once. A pattern is a directory or an import path,
+// which matches all packages below it when followed by /..., e.g. ./...
+//
+// Each mock is named after its interface and meant to be written to the path
+// given by the layout of the generator, see WithLayout. Mocks given the same
+// path are written to one file, they must have the same build constraint and
+// different names. Files that are already generated with the same hash are
+// returned as unchanged, see Request.SkipUnchanged, as are the files of
+// packages that are cached as unchanged, see WithCache. The files are returned
+// sorted by path, the same for every run. Interfaces that can not be mocked,
+// like constraints with type sets, and main packages are skipped. The files
+// of the other interfaces are returned together with the errors of those that
+// failed.
+func (f *Generator) GenerateAll(ctx context.Context, patterns []string) ([]GeneratedFile, error) {
+	return f.generatePlans(ctx, f.planRequests(ctx, patterns, "exported", exportedRequest))
+}
+
+// Check returns the files GenerateAll would write, those that are missing or
+// out of date, without generating them. A file is out of date if the hash in
+// its header differs from the hash of its mocks, which is computed without
+// rendering them. The files are returned without File and Source.
+func (f *Generator) Check(ctx context.Context, patterns []string) ([]GeneratedFile, error) {
+	return checkPlans(f.planRequests(ctx, patterns, "exported", exportedRequest))
+}
+
+// exportedRequest requests a mock named after the type if it is an exported
+// interface
+func exportedRequest(file *file, spec *ast.TypeSpec) (*Request, error) {
+	if _, ok := spec.Type.(*ast.InterfaceType); !ok || !ast.IsExported(spec.Name.Name) {
+		return nil, nil
+	}
+	return &Request{Path: file.path, Name: spec.Name.Name, MockName: spec.Name.Name}, nil
+}
+
+// planned are the mocks planned for the packages matched by patterns
+type planned struct {
+	plans []*mockPlan
+	// dirs are the directories of the packages of the plans
+	dirs map[*mockPlan]string
+	// cached are the files of the packages cached as unchanged
+	cached []GeneratedFile
+	// entries are the cache entries of the planned packages by directory,
+	// without their mocks
+	entries map[string]*cacheEntry
+	err     error
+}
+
+// generatePlans generates the files of the planned mocks, one file for the
+// mocks with the same output, skipping the files that are unchanged. The
+// files are generated by the workers of the generator and returned sorted by
+// path, together with the cached files, the planning error and the errors
+// of the files that failed. The packages of the files are cached once they
+// are written.
+func (f *Generator) generatePlans(ctx context.Context, pl planned) ([]GeneratedFile, error) {
+	groups := groupPlans(pl.plans)
+	results := make([]GeneratedFile, len(groups))
+	errs := make([]error, len(groups)+1)
+	errs[len(groups)] = pl.err
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < f.workers && w < len(groups); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = f.generateMocks(ctx, groups[i], false, true)
+			}
+		}()
+	}
+feed:
+	for i := range groups {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	files := pl.cached
+	for i, file := range results {
+		// Packages are only cached with all their mocks, in files of
+		// their own
+		dirs := map[string]bool{}
+		for _, p := range groups[i] {
+			dirs[pl.dirs[p]] = true
+		}
+		for dir := range dirs {
+			if entry := pl.entries[dir]; entry != nil && errs[i] == nil && len(dirs) == 1 {
+				entry.Mocks = append(entry.Mocks, cachedMock{Path: file.Path, Interfaces: file.Interfaces, Hash: file.Hash})
+			} else {
+				delete(pl.entries, dir)
+			}
+		}
+		if errs[i] == nil {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	f.pending.add(pl.entries)
+	return files, errors.Join(errs...)
+}
+
+// checkPlans returns the files of the planned mocks that are missing or out
+// of date, one file for the mocks with the same output, together with the
+// planning error and the errors of the files that can not be generated
+func checkPlans(pl planned) ([]GeneratedFile, error) {
+	errs := []error{pl.err}
+	var stale []GeneratedFile
+	for _, group := range groupPlans(pl.plans) {
+		hash, err := sharedHash(group)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if output := group[0].output; FileHash(output) != hash {
+			stale = append(stale, GeneratedFile{Path: output, Interfaces: interfaceNames(group), Hash: hash})
+		}
+	}
+	return stale, errors.Join(errs...)
+}
+
+// groupPlans groups the plans by output, the groups are sorted by output and
+// the plans of each group by the names and packages of their interfaces
+func groupPlans(plans []*mockPlan) [][]*mockPlan {
+	sorted := append([]*mockPlan{}, plans...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case a.output != b.output:
+			return a.output < b.output
+		case a.iface.Name != b.iface.Name:
+			return a.iface.Name < b.iface.Name
+		}
+		return a.iface.Package.Dir < b.iface.Package.Dir
+	})
+	var groups [][]*mockPlan
+	for i, p := range sorted {
+		if i > 0 && p.output == sorted[i-1].output {
+			groups[len(groups)-1] = append(groups[len(groups)-1], p)
+			continue
+		}
+		groups = append(groups, []*mockPlan{p})
+	}
+	return groups
+}
+
+// sharedHash returns the hash of the mocks planned to be written to the same
+// file, the hash of the mock if there is only one. It is an error if the
+// mocks have different build constraints or the same name.
+func sharedHash(plans []*mockPlan) (string, error) {
+	if len(plans) == 1 {
+		return plans[0].hash, nil
+	}
+	first := plans[0]
+	names := map[string]*mockPlan{}
+	h := sha256.New()
+	for _, p := range plans {
+		if p.constraint != first.constraint {
+			return "", fmt.Errorf("the mocks of %s and %s can not both be written to %s, they have different build constraints",
+				first.iface.Name, p.iface.Name, p.output)
+		}
+		name := p.mock
+		if p.template != nil {
+			name = p.iface.Name
+		}
+		if other, ok := names[name]; ok {
+			return "", fmt.Errorf("the mocks of %s and %s can not both be written to %s, they are both named %s",
+				other.iface.Name, p.iface.Name, p.output, name)
+		}
+		names[name] = p
+		h.Write([]byte(p.hash + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+// interfaceNames returns the names of the interfaces of the plans
+func interfaceNames(plans []*mockPlan) []string {
+	names := make([]string, len(plans))
+	for i, p := range plans {
+		names[i] = p.iface.Name
+	}
+	return names
+}
+
+// planRequests plans the mocks of the requests toRequest creates for the
+// types of the packages matched by the patterns, types it returns nil for are
+// skipped. The packages are handled by the workers of the
+// generator. Packages cached as unchanged for the kind of requests are not
+// planned, unless their files would get mocks of other packages.
+func (f *Generator) planRequests(ctx context.Context, patterns []string, kind string, toRequest func(*file, *ast.TypeSpec) (*Request, error)) planned {
+	dirs, err := f.expandPatterns(patterns)
+	if err != nil {
+		return planned{err: err}
+	}
+
+	workers := f.workers
+	if workers > len(dirs) {
+		workers = len(dirs)
+	}
+	f.logger.Debug("loading packages", "packages", len(dirs), "workers", workers)
+
+	// Each directory has a slot of its own, which makes the order of the
+	// errors independent of the order the workers finish in
+	results := make([]dirPlans, len(dirs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = f.planRequestsIn(ctx, dirs[i], kind, toRequest, f.cacheEnabled())
+			}
+		}()
+	}
+feed:
+	for i := range dirs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return planned{err: err}
+	}
+
+	outputs := map[string]bool{}
+	for _, r := range results {
+		for _, p := range r.plans {
+			outputs[p.output] = true
+		}
+	}
+	for i, r := range results {
+		for _, file := range r.cached {
+			if outputs[file.Path] {
+				results[i] = f.planRequestsIn(ctx, dirs[i], kind, toRequest, false)
+				break
+			}
+		}
+	}
+
+	pl := planned{dirs: map[*mockPlan]string{}, entries: map[string]*cacheEntry{}}
+	var errs []error
+	for i, r := range results {
+		for _, p := range r.plans {
+			pl.dirs[p] = dirs[i]
+		}
+		pl.plans = append(pl.plans, r.plans...)
+		pl.cached = append(pl.cached, r.cached...)
+		if r.entry != nil {
+			pl.entries[dirs[i]] = r.entry
+		}
+		errs = append(errs, r.errs...)
+	}
+	pl.err = errors.Join(errs...)
+	return pl
+}
+
+// dirPlans are the mocks planned for a package
+type dirPlans struct {
+	plans []*mockPlan
+	// cached are the files of the package if it is cached as unchanged
+	cached []GeneratedFile
+	// entry is the cache entry of the package, without its mocks, if it
+	// is to be cached
+	entry *cacheEntry
+	errs  []error
+}
+
+// planRequestsIn plans the mocks of the requests for the interfaces of the
+// package in dir. With useCache, the cached files are returned instead if
+// the package is cached as unchanged, and a cache entry is created for the
+// package otherwise.
+func (f *Generator) planRequestsIn(ctx context.Context, dir, kind string, toRequest func(*file, *ast.TypeSpec) (*Request, error), useCache bool) dirPlans {
+	var sources map[string]stamp
+	if useCache {
+		if files, ok := f.cachedMocks(dir, kind); ok {
+			f.logger.Debug("package is unchanged", "dir", dir)
+			return dirPlans{cached: files}
+		}
+		// The files are stamped before they are parsed, a file modified
+		// in between is parsed again by the next run
+		sources, _ = sourceStamps(dir)
+	}
+
+	p, err := f.loader.loadDir(dir, "", false)
+	if err != nil {
+		return dirPlans{errs: []error{err}}
+	}
+	if p.name == "main" {
+		f.logger.Debug("skipping main package", "dir", dir)
+		return dirPlans{entry: newCacheEntry(f.cachePath(dir, kind), sources, nil)}
+	}
+
+	var r dirPlans
+	for _, file := range p.files {
+		for _, spec := range file.typeSpecs() {
+			if err := ctx.Err(); err != nil {
+				return dirPlans{}
+			}
+			req, err := toRequest(file, spec)
+			if err != nil {
+				r.errs = append(r.errs, err)
+				continue
+			}
+			if req == nil {
+				continue
+			}
+			plan, err := f.plan(*req)
+			if errors.Is(err, ErrUnsupportedType) {
+				f.logger.Debug("skipping interface", "name", spec.Name.Name, "err", err)
+				continue
+			}
+			if err != nil {
+				r.errs = append(r.errs, err)
+				continue
+			}
+			r.plans = append(r.plans, plan)
+		}
+	}
+	if len(r.errs) == 0 {
+		r.entry = newCacheEntry(f.cachePath(dir, kind), sources, r.plans)
+	}
+	return r
+}
+
+// expandPatterns returns the sorted directories of the packages matched by
+// the patterns
+func (f *Generator) expandPatterns(patterns []string) ([]string, error) {
+	found := map[string]bool{}
+	for _, pattern := range patterns {
+		recursive := pattern == "..." || strings.HasSuffix(pattern, "/...")
+		base := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+		if base == "" {
+			base = "."
+		}
+
+		dir, err := patternDir(base)
+		if err != nil {
+			return nil, err
+		}
+		if !recursive {
+			found[dir] = true
+			continue
+		}
+
+		matched := false
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if path != dir && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			if files, err := f.loader.goFiles(path, false); err == nil && len(files) > 0 {
+				found[path] = true
+				matched = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			f.logger.Warn("pattern matched no packages", "pattern", pattern)
+		}
+	}
+
+	dirs := make([]string, 0, len(found))
+	for dir := range found {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// patternDir returns the absolute directory of a pattern without /..., which
+// is either a directory or the import path of a package
+func patternDir(base string) (string, error) {
+	if info, err := os.Stat(base); err == nil && info.IsDir() {
+		return overlayKey(base), nil
+	} else if build.IsLocalImport(base) || filepath.IsAbs(base) {
+		if err == nil {
+			err = fmt.Errorf("%s is not a directory", base)
+		}
+		return "", err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	ctx := build.Default
+	ctx.Dir = wd
+	bp, err := ctx.Import(base, wd, build.FindOnly)
+	if err != nil {
+		return "", fmt.Errorf("could not find package %s: %w", base, err)
+	}
+	return bp.Dir, nil
+}
@@ -0,0 +1,118 @@
+package mockgen
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAllSharedOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"store.go": "package store\n\nimport (\n\t\"context\"\n\t\"io\"\n)\n\n" +
+			"type Store interface {\n\tGet(ctx context.Context) int\n}\n\ntype Cache interface {\n\tPut(ctx context.Context, r io.Reader)\n}\n",
+	})
+	fileName, err := ParseFileName("mock_{{.Package}}.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout := WithLayout(Layout{FileName: fileName})
+	output := filepath.Join(dir, "mock", "mock_store.go")
+
+	g := New(layout, WithVerify())
+	files, err := g.GenerateAll(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != output || strings.Join(files[0].Interfaces, ",") != "Cache,Store" {
+		t.Fatalf("got %v, want one file with Cache and Store", files)
+	}
+	src := string(files[0].Source)
+	for _, want := range []string{
+		"//mockay:mock Cache Cache\n//mockay:mock Store Store\n",
+		"\"context\"",
+		"\"io\"",
+		"func (m *Cache) Put(",
+		"func (m *Store) Get(",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("the file does not contain %q:\n%s", want, src)
+		}
+	}
+	if _, err := g.WriteFiles(files); err != nil {
+		t.Fatal(err)
+	}
+
+	if stale, err := New(layout).Check(context.Background(), []string{dir}); err != nil || len(stale) != 0 {
+		t.Errorf("got %v, %v from Check, want the file to be up to date", stale, err)
+	}
+	again, err := New(layout).GenerateAll(context.Background(), []string{dir})
+	if err != nil || len(again) != 1 || !again[0].Unchanged {
+		t.Errorf("got %v, %v, want the file to be unchanged", again, err)
+	}
+	found := map[string]bool{}
+	for _, m := range parseMocks(output) {
+		found[m.iface+" "+m.name] = true
+	}
+	if !found["Cache Cache"] || !found["Store Store"] {
+		t.Errorf("found the mocks %v in the header", found)
+	}
+}
+
+func TestGenerateAllSharedOutputConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// fileName is the file name template of the layout
+		fileName string
+		want     string
+	}{
+		{
+			name: "build constraints",
+			files: map[string]string{
+				"a/store_linux.go": "package a\n\ntype Store interface {\n\tGet() int\n}\n",
+				"a/cache.go":       "package a\n\ntype Cache interface {\n\tPut(n int)\n}\n",
+			},
+			fileName: "mock_{{.Package}}.go",
+			want:     "different build constraints",
+		},
+		{
+			name: "names",
+			files: map[string]string{
+				"a/store.go": "package a\n\ntype Store interface {\n\tGet() int\n}\n",
+				"b/store.go": "package b\n\ntype Store interface {\n\tPut(n int)\n}\n",
+			},
+			want: "both named Store",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"go.mod": "module example.com/m\n"})
+			writeFiles(t, dir, test.files)
+			layout := Layout{Dir: filepath.Join(dir, "mock")}
+			if test.fileName != "" {
+				fileName, err := ParseFileName(test.fileName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				layout.FileName = fileName
+			}
+			g := New(WithLayout(layout))
+			for name, fn := range map[string]func(context.Context, []string) ([]GeneratedFile, error){
+				"GenerateAll": g.GenerateAll,
+				"Check":       g.Check,
+			} {
+				files, err := fn(context.Background(), []string{filepath.Join(dir, "...")})
+				if err == nil || !strings.Contains(err.Error(), test.want) {
+					t.Errorf("%s: expected an error about %s, got %v", name, test.want, err)
+				}
+				if len(files) != 0 {
+					t.Errorf("%s: got %d files, expected none", name, len(files))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package mockgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCacheDir returns the directory packages with unchanged mocks are
+// cached in by default, in the user cache directory
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mockay"), nil
+}
+
+// WithCache makes GenerateAll, GenerateAnnotated and their checks cache in
+// dir which mocks the packages have, once they are written with WriteFiles.
+// Packages whose files and mocks have not been modified since are not parsed
+// again, their mocks are returned as unchanged. The cache is not used with
+// an overlay.
+func WithCache(dir string) Option {
+	return func(f *Generator) { f.cacheDir = dir }
+}
+
+// stamp is what is compared to find modified files
+type stamp struct {
+	ModTime int64 `json:"modTime"`
+	Size    int64 `json:"size"`
+}
+
+func stampOf(info fs.FileInfo) stamp {
+	return stamp{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+}
+
+// pendingEntries are cache entries waiting for their mocks to be written
+type pendingEntries struct {
+	mu      sync.Mutex
+	entries []*cacheEntry
+}
+
+// add adds entries, which are cached by the next call to WriteFiles
+func (p *pendingEntries) add(entries map[string]*cacheEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, entry := range entries {
+		p.entries = append(p.entries, entry)
+	}
+}
+
+// take returns and removes all entries
+func (p *pendingEntries) take() []*cacheEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entries := p.entries
+	p.entries = nil
+	return entries
+}
+
+// cacheEntry is cached for a package: the stamps of the files its mocks
+// depend on, and its mocks with the stamps they were written with
+type cacheEntry struct {
+	// path is the file the entry is cached in
+	path string
+	// Sources are the Go files of the package, its go.mod and the files of
+	// other packages its interfaces embed
+	Sources map[string]stamp `json:"sources"`
+	Mocks   []cachedMock     `json:"mocks"`
+}
+
+// cachedMock is a file of mocks of a package
+type cachedMock struct {
+	Path       string   `json:"path"`
+	Interfaces []string `json:"interfaces"`
+	Hash       string   `json:"hash"`
+	Stamp      stamp    `json:"stamp"`
+}
+
+// cacheEnabled reports if packages are cached
+func (f *Generator) cacheEnabled() bool {
+	return f.cacheDir != "" && f.overlay == nil
+}
+
+// cachePath returns the file the entry of the package in dir is cached in,
+// named after the hash of the directory, the kind of requests and the
+// settings of the generator that decide which mocks are generated and how
+func (f *Generator) cachePath(dir, kind string) string {
+	h := sha256.New()
+	for _, part := range []string{hashVersion, kind, overlayKey(dir), f.settings()} {
+		h.Write([]byte(part + "\n"))
+	}
+	return filepath.Join(f.cacheDir, "packages", hex.EncodeToString(h.Sum(nil)[:16]))
+}
+
+// settings returns the settings of the generator that the mocks depend on as
+// text
+func (f *Generator) settings() string {
+	lines := []string{"style " + f.style, "layout " + strconv.Itoa(int(f.layout.Package)) + " " + f.layout.Dir}
+	if f.template != nil {
+		lines = append(lines, "template "+f.template.Tree.Root.String())
+	}
+	if f.layout.FileName != nil {
+		lines = append(lines, "filename "+f.layout.FileName.Tree.Root.String())
+	}
+	if f.constraint != nil {
+		lines = append(lines, "build "+*f.constraint)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sourceStamps returns the stamps of the Go files in the directory of a
+// package and of the go.mod of its module. The directory itself is left out,
+// it is modified when mocks are written to a directory in it.
+func sourceStamps(dir string) (map[string]stamp, error) {
+	stamps := map[string]stamp{}
+	add := func(path string) error {
+		info, err := os.Stat(path)
+		if err == nil {
+			stamps[path] = stampOf(info)
+		}
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			if err := add(filepath.Join(dir, e.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if root, _ := findModule(dir); root != "" {
+		if err := add(filepath.Join(root, "go.mod")); err != nil {
+			return nil, err
+		}
+	}
+	return stamps, nil
+}
+
+// cachedMocks returns the mocks cached for the package in dir as unchanged
+// files, ok is false unless the package has an entry and neither its files
+// nor its mocks have been modified since
+func (f *Generator) cachedMocks(dir, kind string) (files []GeneratedFile, ok bool) {
+	content, err := os.ReadFile(f.cachePath(dir, kind))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, false
+	}
+	current, err := sourceStamps(dir)
+	if err != nil || !sameStamps(current, entry.Sources) {
+		return nil, false
+	}
+	for path, s := range entry.Sources {
+		if _, ok := current[path]; ok {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || stampOf(info) != s {
+			return nil, false
+		}
+	}
+	for _, mock := range entry.Mocks {
+		if info, err := os.Stat(mock.Path); err != nil || stampOf(info) != mock.Stamp {
+			return nil, false
+		}
+		files = append(files, GeneratedFile{Path: mock.Path, Interfaces: mock.Interfaces, Hash: mock.Hash, Unchanged: true})
+	}
+	return files, true
+}
+
+// sameStamps reports if the files stamped in current have the same stamps
+// in cached, files added since are not in cached
+func sameStamps(current, cached map[string]stamp) bool {
+	for path, s := range current {
+		if cached[path] != s {
+			return false
+		}
+	}
+	return true
+}
+
+// saveCache caches the entries of the packages whose mocks were generated,
+// once the mocks are written
+func (f *Generator) saveCache(entries []*cacheEntry) {
+	for _, entry := range entries {
+		path := entry.path
+		for i, mock := range entry.Mocks {
+			info, err := os.Stat(mock.Path)
+			if err != nil {
+				f.logger.Debug("not caching package", "mock", mock.Path, "err", err)
+				entry = nil
+				break
+			}
+			entry.Mocks[i].Stamp = stampOf(info)
+		}
+		if entry == nil {
+			continue
+		}
+		content, err := json.Marshal(entry)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0770)
+		}
+		if err == nil {
+			err = os.WriteFile(path, content, 0660)
+		}
+		if err != nil {
+			f.logger.Warn("could not cache package", "path", path, "err", err)
+		}
+	}
+}
+
+// WriteFiles writes generated files, creating their directories. Files that
+// are unchanged are not written. The paths of the written files are
+// returned. The packages the files were generated for are cached once all
+// files are written, see WithCache.
+func (f *Generator) WriteFiles(files []GeneratedFile) ([]string, error) {
+	var written []string
+	for _, file := range files {
+		if file.Unchanged {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0770); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(file.Path, file.Source, 0660); err != nil {
+			return written, err
+		}
+		written = append(written, file.Path)
+	}
+
+	f.saveCache(f.pending.take())
+	return written, nil
+}
+
+// newCacheEntry creates the entry of a package with the stamps of its files,
+// and of the files of other packages the planned interfaces and their
+// methods are declared in. It is nil if the files could not be stamped.
+func newCacheEntry(path string, sources map[string]stamp, plans []*mockPlan) *cacheEntry {
+	if sources == nil {
+		return nil
+	}
+	for _, p := range plans {
+		declared := []string{p.iface.Position.Filename}
+		for _, m := range p.iface.Methods {
+			declared = append(declared, m.Position.Filename)
+		}
+		for _, path := range declared {
+			if _, ok := sources[path]; ok || path == "" {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil
+			}
+			sources[path] = stampOf(info)
+		}
+	}
+	return &cacheEntry{path: path, Sources: sources}
+}
@@ -0,0 +1,115 @@
+package mockgen
+
+import (
+	"go/build/constraint"
+	"path/filepath"
+	"strings"
+)
+
+// knownOS and knownArch are the values of GOOS and GOARCH that are
+// recognized in file names, as listed in go/build
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+		"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+		"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+		"windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true,
+		"arm64": true, "arm64be": true, "loong64": true, "mips": true, "mipsle": true,
+		"mips64": true, "mips64le": true, "mips64p32": true, "mips64p32le": true,
+		"ppc": true, "ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true,
+		"s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// WithBuildConstraint sets the //go:build constraint of generated mocks, as
+// the expression after //go:build, instead of using the constraint of the
+// file declaring the interface. Mocks are generated without a constraint if
+// expr is empty.
+func WithBuildConstraint(expr string) Option {
+	return func(f *Generator) { f.constraint = &expr }
+}
+
+// buildConstraint returns the build constraint of the file as the expression
+// of a //go:build line, empty if it has none. The constraint implied by a
+// _GOOS or _GOARCH suffix of the file name is included.
+func (f *file) buildConstraint() string {
+	var expr constraint.Expr
+	var plusBuild []constraint.Expr
+	for _, group := range f.astFile.Comments {
+		if group.Pos() >= f.astFile.Package {
+			break
+		}
+		for _, c := range group.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				expr, _ = constraint.Parse(c.Text)
+			case constraint.IsPlusBuild(c.Text):
+				if x, err := constraint.Parse(c.Text); err == nil {
+					plusBuild = append(plusBuild, x)
+				}
+			}
+		}
+	}
+	if expr == nil {
+		// Files with only the old syntax require all of the lines
+		for _, x := range plusBuild {
+			expr = and(expr, x)
+		}
+	}
+
+	expr = and(expr, fileNameConstraint(filepath.Base(f.path)))
+	if expr == nil {
+		return ""
+	}
+	return expr.String()
+}
+
+// fileNameConstraint returns the constraint implied by the name of a file,
+// e.g. linux && amd64 for x_linux_amd64.go, nil if there is none
+func fileNameConstraint(name string) constraint.Expr {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".go"), "_test")
+	// The part before the first _ is never a constraint, so that linux.go
+	// is not restricted to linux
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return nil
+	}
+	parts := strings.Split(name[i:], "_")
+	n := len(parts)
+	if n >= 2 && knownOS[parts[n-2]] && knownArch[parts[n-1]] {
+		return and(&constraint.TagExpr{Tag: parts[n-2]}, &constraint.TagExpr{Tag: parts[n-1]})
+	}
+	if knownOS[parts[n-1]] || knownArch[parts[n-1]] {
+		return &constraint.TagExpr{Tag: parts[n-1]}
+	}
+	return nil
+}
+
+// and returns x && y, either may be nil
+func and(x, y constraint.Expr) constraint.Expr {
+	switch {
+	case x == nil:
+		return y
+	case y == nil:
+		return x
+	}
+	return &constraint.AndExpr{X: x, Y: y}
+}
+
+// withConstraint returns the source of a generated file with a //go:build
+// line for the constraint, unless the expression is empty or the file
+// already has one, as rendered by a template
+func withConstraint(src []byte, expr string) []byte {
+	if expr == "" {
+		return src
+	}
+	for _, line := range strings.Split(string(headerOf(src)), "\n") {
+		if constraint.IsGoBuild(strings.TrimSpace(line)) {
+			return src
+		}
+	}
+	return append([]byte("//go:build "+expr+"\n\n"), src...)
+}
@@ -0,0 +1,92 @@
+package mockgen
+
+import (
+	"context"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildConstraint(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		header string
+		// after is the source after the package clause
+		after string
+		want  string
+	}{
+		{name: "none", file: "store.go", want: ""},
+		{name: "go:build", file: "store.go", header: "//go:build linux && !cgo\n\n", want: "linux && !cgo"},
+		{name: "+build lines", file: "store.go", header: "// +build linux darwin\n// +build amd64\n\n", want: "(linux || darwin) && amd64"},
+		{name: "+build with commas", file: "store.go", header: "// +build linux,amd64 windows\n\n", want: "(linux && amd64) || windows"},
+		{name: "go:build before +build", file: "store.go", header: "//go:build linux || darwin\n// +build linux darwin\n\n", want: "linux || darwin"},
+		{name: "go:build after +build", file: "store.go", header: "// Copyright\n\n// +build linux\n\n//go:build linux\n\n", want: "linux"},
+		{name: "after the package clause", file: "store.go", after: "\n//go:build linux\n", want: ""},
+
+		{name: "GOOS suffix", file: "store_linux.go", want: "linux"},
+		{name: "GOARCH suffix of a test", file: "store_amd64_test.go", want: "amd64"},
+		{name: "GOOS and GOARCH", file: "store_windows_arm64.go", want: "windows && arm64"},
+		{name: "GOOS and GOARCH of a test", file: "store_linux_amd64_test.go", want: "linux && amd64"},
+		{name: "GOARCH before GOOS", file: "store_amd64_linux.go", want: "linux"},
+		{name: "only a GOOS", file: "linux.go", want: ""},
+		{name: "only a test of a GOOS", file: "linux_test.go", want: ""},
+		{name: "unknown suffix", file: "store_mock.go", want: ""},
+
+		{name: "go:build and file name", file: "store_linux.go", header: "//go:build cgo\n\n", want: "cgo && linux"},
+		{name: "+build and file name", file: "store_amd64_test.go", header: "// +build integration\n\n", want: "integration && amd64"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := test.header + "package store\n" + test.after
+			f, err := openFile(token.NewFileSet(), test.file, map[string][]byte{overlayKey(test.file): []byte(src)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.buildConstraint(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGenerateBuildConstraint(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":         "module example.com/m\n",
+		"store_linux.go": "// +build cgo\n\npackage store\n\ntype Store interface {\n\tGet() int\n}\n",
+	})
+	path := filepath.Join(dir, "store_linux.go")
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{name: "of the file", want: "\n//go:build cgo && linux\n"},
+		{name: "set", options: []Option{WithBuildConstraint("integration")}, want: "\n//go:build integration\n"},
+		{name: "removed", options: []Option{WithBuildConstraint("")}, want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := New(test.options...).GenerateFiles(context.Background(), Request{Path: path, Name: "Store"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			src := string(files[0].Source)
+			if test.want == "" {
+				if strings.Contains(src, "//go:build") {
+					t.Errorf("the mock has a build constraint:\n%s", src)
+				}
+				return
+			}
+			if i := strings.Index(src, test.want); i < 0 || i > strings.Index(src, "\npackage ") {
+				t.Errorf("the mock does not have %q before its package clause:\n%s", test.want, src)
+			}
+			if strings.Count(src, "//go:build") != 1 {
+				t.Errorf("the mock has more than one build constraint:\n%s", src)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package mockgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// DefaultStyle is the name of the built in emitter, used when no style is set
+const DefaultStyle = "default"
+
+// Emitter creates the declarations of the mock of an interface, allowing for
+// other mock styles than the built in one
+type Emitter interface {
+	// Emit returns the declarations of the mock. Types of the interface are
+	// turned into expressions with mock.Imports.TypeExpr, which imports the
+	// packages they need. Other packages used by the declarations are
+	// returned, they are referred to with their names.
+	Emit(mock *Mock) ([]ast.Decl, []model.Import, error)
+}
+
+// Mock is the mock an Emitter creates the declarations of
+type Mock struct {
+	// Name is the name of the mock type
+	Name string
+	// Interface is the interface to mock
+	Interface *model.Interface
+	// Imports are the imports of the generated file
+	Imports *Imports
+}
+
+// EmitterFunc is a function used as an Emitter
+type EmitterFunc func(mock *Mock) ([]ast.Decl, []model.Import, error)
+
+// Emit calls fn
+func (fn EmitterFunc) Emit(mock *Mock) ([]ast.Decl, []model.Import, error) {
+	return fn(mock)
+}
+
+var (
+	emittersMu sync.RWMutex
+	emitters   = map[string]Emitter{
+		DefaultStyle: defaultEmitter{},
+	}
+)
+
+// RegisterEmitter makes an emitter available as a style with the name. It
+// panics if the name is already registered.
+func RegisterEmitter(name string, emitter Emitter) {
+	emittersMu.Lock()
+	defer emittersMu.Unlock()
+	if emitter == nil {
+		panic("mockgen: RegisterEmitter emitter is nil")
+	}
+	if _, ok := emitters[name]; ok {
+		panic("mockgen: RegisterEmitter called twice for style " + name)
+	}
+	emitters[name] = emitter
+}
+
+// Styles returns the names of the registered emitters, sorted
+func Styles() []string {
+	emittersMu.RLock()
+	defer emittersMu.RUnlock()
+	names := make([]string, 0, len(emitters))
+	for name := range emitters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupEmitter(name string) (Emitter, error) {
+	if name == "" {
+		name = DefaultStyle
+	}
+	emittersMu.RLock()
+	emitter, ok := emitters[name]
+	emittersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown style %s, the available styles are %s", name, strings.Join(Styles(), ", "))
+	}
+	return emitter, nil
+}
+
+// defaultEmitter creates a mock struct with a function field for each method,
+// and helper methods that record calls and set return values
+type defaultEmitter struct{}
+
+func (defaultEmitter) Emit(mock *Mock) ([]ast.Decl, []model.Import, error) {
+	iface, imports := mock.Interface, mock.Imports
+	var typeParams *ast.FieldList
+	var recv ast.Expr = ident(mock.Name)
+	if len(iface.TypeParams) > 0 {
+		typeParams = &ast.FieldList{}
+		var names []ast.Expr
+		for _, p := range iface.TypeParams {
+			typeParams.List = append(typeParams.List, field(p.Name, imports.TypeExpr(p.Type)))
+			names = append(names, ident(p.Name))
+		}
+		if len(names) == 1 {
+			recv = &ast.IndexExpr{X: recv, Index: names[0]}
+		} else {
+			recv = &ast.IndexListExpr{X: recv, Indices: names}
+		}
+	}
+
+	var fieldList []*ast.Field
+	var funcDecs []ast.Decl
+	for _, method := range iface.Methods {
+		m := newMockMethod(recv, iface.Name, method, imports)
+		fieldList = append(fieldList, m.fields()...)
+		funcDecs = append(funcDecs, m.decls()...)
+	}
+
+	genStruct := &ast.GenDecl{
+		Doc: docComment(mock.Name+" is a mock implementation of "+iface.Name, iface.Doc),
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: &ast.Ident{
+					Name: mock.Name,
+				},
+				TypeParams: typeParams,
+				Type: &ast.StructType{
+					Fields: &ast.FieldList{
+						List: fieldList,
+					},
+				},
+			},
+		},
+	}
+
+	decls := append([]ast.Decl{genStruct}, funcDecs...)
+	return decls, []model.Import{{Name: "sync", Path: "sync"}}, nil
+}
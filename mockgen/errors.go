@@ -0,0 +1,232 @@
+package mockgen
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+var (
+	// ErrNoTarget is returned when neither a position nor a name of an
+	// interface is given
+	ErrNoTarget = errors.New("no interface selected, a position or a name is needed")
+	// ErrInvalidPosition is returned when a position is outside of the file
+	ErrInvalidPosition = errors.New("invalid position")
+	// ErrInterfaceNotFound is matched by a *NotFoundError
+	ErrInterfaceNotFound = errors.New("interface not found")
+	// ErrUnsupportedType is matched by an *UnsupportedTypeError
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrUnexported is matched by an *UnexportedError
+	ErrUnexported = errors.New("interface can not be implemented outside of its package")
+)
+
+// maxCandidates is the number of interfaces listed in a NotFoundError
+const maxCandidates = 5
+
+// NotFoundError is returned when there is no interface at a position, or no
+// interface with a name
+type NotFoundError struct {
+	// Name is the name that was looked for, it is empty if there was no
+	// name at the position
+	Name string
+	// Position is the position that was looked at, it is zero when looking
+	// for an interface by name
+	Position model.Position
+	// Candidates are other interfaces declared in the file, closest to the
+	// position first, or in the package when looking for a name
+	Candidates []Candidate
+}
+
+// Candidate is an interface that could have been meant
+type Candidate struct {
+	Name     string
+	Position model.Position
+}
+
+func (e *NotFoundError) Error() string {
+	var b strings.Builder
+	b.WriteString("interface ")
+	if e.Name != "" {
+		b.WriteString(e.Name + " ")
+	}
+	b.WriteString("not found")
+	if e.Position.Line > 0 {
+		b.WriteString(" at " + formatPosition(e.Position))
+	}
+	for i, c := range e.Candidates {
+		if i == 0 {
+			b.WriteString(", interfaces declared nearby are ")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s (%s)", c.Name, formatPosition(c.Position))
+	}
+	return b.String()
+}
+
+// Is makes errors.Is(err, ErrInterfaceNotFound) true
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrInterfaceNotFound
+}
+
+// UnsupportedTypeError is returned for types that can not be mocked, like
+// other types than interfaces or interfaces with type sets
+type UnsupportedTypeError struct {
+	Name     string
+	Position model.Position
+	Reason   string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("%s: can not mock %s: %s", formatPosition(e.Position), e.Name, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrUnsupportedType) true
+func (e *UnsupportedTypeError) Is(target error) bool {
+	return target == ErrUnsupportedType
+}
+
+// UnexportedError is returned when the mock of an interface is generated in
+// another package than the interface, but the interface has unexported
+// methods or refers to unexported types, which only its own package can
+// implement or name. The mock has to be generated in the package of the
+// interface instead, e.g. in a _test.go file with the TestPackage layout.
+type UnexportedError struct {
+	Interface string
+	Position  model.Position
+	// Package is the name of the package of the interface
+	Package string
+	// Uses are the unexported methods and the unexported types used by
+	// methods, in the order of the methods
+	Uses []UnexportedUse
+}
+
+// UnexportedUse is an unexported method of an interface, or an unexported
+// type used by it
+type UnexportedUse struct {
+	// Method is the name of the method, empty for types used by the type
+	// parameters of the interface
+	Method string
+	// Position is the position of the method, or of the interface
+	Position model.Position
+	// Type is the unexported type, qualified with the name of its package.
+	// It is empty if the method itself is unexported.
+	Type string
+	// TypePosition is where Type is declared, zero if it was not found
+	TypePosition model.Position
+}
+
+func (e *UnexportedError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s can only be mocked in package %s, it can not be implemented in other packages", formatPosition(e.Position), e.Interface, e.Package)
+	for _, u := range e.Uses {
+		fmt.Fprintf(&b, "\n\t%s: ", formatPosition(u.Position))
+		switch {
+		case u.Type == "":
+			fmt.Fprintf(&b, "method %s is unexported", u.Method)
+		case u.Method == "":
+			fmt.Fprintf(&b, "type parameters use the unexported type %s", u.Type)
+		default:
+			fmt.Fprintf(&b, "method %s uses the unexported type %s", u.Method, u.Type)
+		}
+		if u.TypePosition.Filename != "" {
+			fmt.Fprintf(&b, " (declared at %s)", formatPosition(u.TypePosition))
+		}
+	}
+	return b.String()
+}
+
+// Is makes errors.Is(err, ErrUnexported) true
+func (e *UnexportedError) Is(target error) bool {
+	return target == ErrUnexported
+}
+
+// ParseError is returned when a Go file can not be parsed
+type ParseError struct {
+	Position model.Position
+	Msg      string
+	// Err is the error returned by the parser, usually a scanner.ErrorList
+	// with all errors in the file
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return formatPosition(e.Position) + ": " + e.Msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyError is returned when a generated mock does not type check
+type VerifyError struct {
+	// Path is where the mock was meant to be written
+	Path      string
+	Interface string
+	Problems  []VerifyProblem
+}
+
+// VerifyProblem is an error found when type checking a mock
+type VerifyProblem struct {
+	// Position is the position in the generated source, or of the interface
+	// when the mock does not implement it
+	Position model.Position
+	Msg      string
+	// Source is the generated line with the error, without indentation
+	Source string
+	// Method is the method of the interface the erroneous code was generated
+	// for, empty if it is not generated for a single method
+	Method string
+	// MethodPosition is where Method is declared
+	MethodPosition model.Position
+}
+
+func (e *VerifyError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "the mock of %s in %s does not type check", e.Interface, e.Path)
+	for _, p := range e.Problems {
+		b.WriteString("\n\t")
+		if p.Method != "" {
+			fmt.Fprintf(&b, "%s: %s.%s: ", formatPosition(p.MethodPosition), e.Interface, p.Method)
+		}
+		b.WriteString(p.Msg)
+		if p.Source != "" {
+			fmt.Fprintf(&b, ", in %q (%s)", p.Source, formatPosition(p.Position))
+		}
+	}
+	return b.String()
+}
+
+// newParseError converts an error from the parser, the position is the one of
+// the first error
+func newParseError(path string, err error) error {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) || len(list) == 0 {
+		return err
+	}
+	return &ParseError{
+		Position: positionOf(path, list[0].Pos),
+		Msg:      list[0].Msg,
+		Err:      err,
+	}
+}
+
+func positionOf(path string, p token.Position) model.Position {
+	return model.Position{
+		Filename: overlayKey(path),
+		Line:     p.Line,
+		Column:   p.Column,
+		Offset:   p.Offset,
+	}
+}
+
+func formatPosition(p model.Position) string {
+	if p.Line == 0 {
+		return p.Filename
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
@@ -0,0 +1,125 @@
+package mockgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// wrapped returns err as the CLI and GenerateAll return it, wrapped with
+// context and joined with other errors
+func wrapped(err error) []error {
+	return []error{
+		err,
+		fmt.Errorf("mockay gen: %w", err),
+		errors.Join(errors.New("other error"), fmt.Errorf("store: %w", err)),
+	}
+}
+
+func TestNotFoundError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.go")
+	writeFiles(t, dir, map[string]string{
+		"store.go": "package store\n\ntype Reader interface {\n\tRead() int\n}\n\nvar x = 1\n\ntype Writer interface {\n\tWrite(int)\n}\n",
+		"other.go": "package store\n\ntype Closer interface {\n\tClose() error\n}\n",
+	})
+	reader := Candidate{Name: "Reader", Position: model.Position{Filename: path, Line: 3, Column: 6, Offset: 20}}
+	writer := Candidate{Name: "Writer", Position: model.Position{Filename: path, Line: 9, Column: 6, Offset: 70}}
+	closer := Candidate{Name: "Closer", Position: model.Position{Filename: filepath.Join(dir, "other.go"), Line: 3, Column: 6, Offset: 20}}
+
+	tests := []struct {
+		name string
+		req  Request
+		want NotFoundError
+	}{
+		{
+			name: "position",
+			req:  Request{Path: path, Position: &Position{X: 7, Y: 9}},
+			want: NotFoundError{
+				Position:   model.Position{Filename: path, Line: 7, Column: 9, Offset: 62},
+				Candidates: []Candidate{writer, reader},
+			},
+		},
+		{
+			name: "name",
+			req:  Request{Path: path, Name: "Store"},
+			want: NotFoundError{
+				Name:       "Store",
+				Candidates: []Candidate{closer, reader, writer},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New().GenerateFiles(context.Background(), test.req)
+			for _, err := range wrapped(err) {
+				if !errors.Is(err, ErrInterfaceNotFound) {
+					t.Errorf("%q is not ErrInterfaceNotFound", err)
+				}
+				var notFound *NotFoundError
+				if !errors.As(err, &notFound) {
+					t.Fatalf("%q is not a *NotFoundError", err)
+				}
+				if !reflect.DeepEqual(*notFound, test.want) {
+					t.Errorf("got %+v, want %+v", *notFound, test.want)
+				}
+				if errors.Is(err, ErrUnsupportedType) || errors.Is(err, ErrNoTarget) {
+					t.Errorf("%q matches other errors", err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrorIs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.go")
+	writeFiles(t, dir, map[string]string{
+		"store.go": "package store\n\ntype Store interface {\n\tGet() int int\n}\n",
+	})
+
+	_, err := New().GenerateFiles(context.Background(), Request{Path: path, Name: "Store"})
+	for _, err := range wrapped(err) {
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("%q is not a *ParseError", err)
+		}
+		if want := (model.Position{Filename: path, Line: 4, Column: 12, Offset: 49}); parseErr.Position != want {
+			t.Errorf("got position %+v, want %+v", parseErr.Position, want)
+		}
+		var list scanner.ErrorList
+		if !errors.As(err, &list) || len(list) == 0 {
+			t.Errorf("%q does not wrap the errors of the parser", err)
+		}
+		if errors.Is(err, ErrInterfaceNotFound) {
+			t.Errorf("%q is ErrInterfaceNotFound", err)
+		}
+	}
+}
+
+func TestUnsupportedTypeErrorIs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "num.go")
+	writeFiles(t, dir, map[string]string{
+		"num.go": "package num\n\ntype Number interface {\n\t~int | ~float64\n}\n\ntype Count struct{}\n",
+	})
+
+	for _, name := range []string{"Number", "Count"} {
+		_, err := New().GenerateFiles(context.Background(), Request{Path: path, Name: name})
+		for _, err := range wrapped(err) {
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Errorf("%q is not ErrUnsupportedType", err)
+			}
+			var unsupported *UnsupportedTypeError
+			if !errors.As(err, &unsupported) || unsupported.Name != name || unsupported.Position.Filename != path {
+				t.Errorf("%q is not an *UnsupportedTypeError of %s in %s", err, name, path)
+			}
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package mockgen
 
 import (
-	"errors"
+	"bufio"
+	"bytes"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type file struct {
@@ -15,24 +21,73 @@ type file struct {
 	path    string
 }
 
-func openFile(path string) (*file, error) {
-	_, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, errors.New("file or directory does not exist")
+func openFile(fset *token.FileSet, path string, overlay map[string][]byte) (*file, error) {
+	src, ok := overlay[overlayKey(path)]
+	if !ok {
+		var err error
+		src, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
 		}
-		return nil, err
 	}
 
-	fset := token.NewFileSet()
-	astFile, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	astFile, err := parser.ParseFile(fset, path, src, parser.ParseComments)
 	if err != nil {
-		return nil, err
+		return nil, newParseError(path, err)
 	}
 
 	return &file{
 		astFile: astFile,
 		fset:    fset,
+		src:     src,
 		path:    path,
 	}, nil
 }
+
+// overlayKey returns the key used for a path in an overlay
+func overlayKey(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// ParseArchive parses the archive format used by editors to pass modified
+// buffers, as used by the -modified flag of guru and gopls. Each file in the
+// archive consists of its name and its size in bytes, each on its own line,
+// followed by the contents of the file.
+func ParseArchive(r io.Reader) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	reader := bufio.NewReader(r)
+	for {
+		name, err := reader.ReadString('\n')
+		if err == io.EOF && name == "" {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read file name from archive: %w", err)
+		}
+		name = strings.TrimSuffix(name, "\n")
+
+		sizeLine, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("could not read size of %s from archive: %w", name, err)
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(sizeLine))
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid size of %s in archive: %q", name, strings.TrimSpace(sizeLine))
+		}
+
+		// The size is not trusted to allocate the contents up front, a bogus
+		// size only makes the read fail at the end of the archive
+		var content bytes.Buffer
+		if _, err := io.CopyN(&content, reader, int64(size)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("could not read contents of %s from archive: %w", name, err)
+		}
+		files[name] = content.Bytes()
+	}
+}
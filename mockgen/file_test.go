@@ -0,0 +1,41 @@
+package mockgen
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseArchive(t *testing.T) {
+	files, err := ParseArchive(strings.NewReader("a.go\n9\npackage ab.go\n0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || string(files["a.go"]) != "package a" || len(files["b.go"]) != 0 {
+		t.Errorf("unexpected files %q", files)
+	}
+
+	tests := []struct {
+		name    string
+		archive string
+		want    error
+	}{
+		{name: "huge size", archive: "a.go\n9223372036854775807\npackage a\n", want: io.ErrUnexpectedEOF},
+		{name: "truncated", archive: "a.go\n100\npackage a\n", want: io.ErrUnexpectedEOF},
+		{name: "negative size", archive: "a.go\n-1\n"},
+		{name: "invalid size", archive: "a.go\nten\n"},
+		{name: "missing size", archive: "a.go\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseArchive(strings.NewReader(test.archive))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if test.want != nil && !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,26 @@
 package mockgen
 
 import (
+	"bytes"
+	"fmt"
 	"go/ast"
+	"go/token"
+	"sort"
+	"unicode/utf8"
 )
 
-func (f *file) findAtPosition(findFunc func(ast.Node) bool, line, column int) ast.Node {
+func (f *file) findAtPosition(findFunc func(ast.Node) bool, pos token.Pos) ast.Node {
 	var found ast.Node
 	abortableInspect(f.astFile, func(n ast.Node) (bool, bool) {
 		if n == nil {
 			return true, false
 		}
 
-		pos := f.fset.Position(n.Pos())
-		end := f.fset.Position(n.End())
-
-		if line < pos.Line || (line == pos.Line && column < pos.Column) {
+		if pos < n.Pos() {
 			return false, true
 		}
 
-		if line < end.Line || (line == end.Line && column <= end.Column) {
+		if pos <= n.End() {
 			if findFunc(n) {
 				found = n
 				return false, true
@@ -29,6 +31,53 @@ func (f *file) findAtPosition(findFunc func(ast.Node) bool, line, column int) as
 	return found
 }
 
+// tokenPos converts a position to a position in the file set
+func (f *file) tokenPos(p Position) (token.Pos, error) {
+	tokFile := f.fset.File(f.astFile.Pos())
+
+	if p.X == 0 {
+		if p.Offset < 0 || p.Offset > tokFile.Size() {
+			return token.NoPos, fmt.Errorf("%w: offset %d is outside of the file", ErrInvalidPosition, p.Offset)
+		}
+		return tokFile.Pos(p.Offset), nil
+	}
+
+	if p.X < 1 || p.X > tokFile.LineCount() || p.Y < 1 {
+		return token.NoPos, fmt.Errorf("%w: line %d is outside of the file", ErrInvalidPosition, p.X)
+	}
+	lineStart := tokFile.Offset(tokFile.LineStart(p.X))
+	line := f.src[lineStart:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+
+	column := p.Y - 1
+	if p.Encoding == UTF16 {
+		column = utf16ToByteColumn(line, column)
+	}
+	if column > len(line) {
+		return token.NoPos, fmt.Errorf("%w: column %d is outside of line %d", ErrInvalidPosition, p.Y, p.X)
+	}
+	return tokFile.Pos(lineStart + column), nil
+}
+
+// utf16ToByteColumn converts a zero based column counted in UTF-16 code units
+// to a zero based column counted in bytes
+func utf16ToByteColumn(line []byte, column int) int {
+	offset := 0
+	for column > 0 && offset < len(line) {
+		r, size := utf8.DecodeRune(line[offset:])
+		if r >= 0x10000 {
+			// Encoded as a surrogate pair
+			column -= 2
+		} else {
+			column--
+		}
+		offset += size
+	}
+	return offset + column
+}
+
 func abortableInspect(n ast.Node, f func(ast.Node) (bool, bool)) {
 	aborted := false
 	ast.Inspect(n, func(n ast.Node) bool {
@@ -43,3 +92,69 @@ func abortableInspect(n ast.Node, f func(ast.Node) (bool, bool)) {
 		return goDown
 	})
 }
+
+// typeSpecDoc returns the doc comment of a type spec, which is attached to the
+// declaration when the declaration only contains that spec
+func (f *file) typeSpecDoc(spec *ast.TypeSpec) *ast.CommentGroup {
+	if spec.Doc != nil {
+		return spec.Doc
+	}
+	for _, decl := range f.astFile.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if ok && len(gen.Specs) == 1 && gen.Specs[0] == spec {
+			return gen.Doc
+		}
+	}
+	return nil
+}
+
+// typeSpecs returns the types declared at the top level of the file
+func (f *file) typeSpecs() []*ast.TypeSpec {
+	var specs []*ast.TypeSpec
+	for _, decl := range f.astFile.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			specs = append(specs, spec.(*ast.TypeSpec))
+		}
+	}
+	return specs
+}
+
+// interfaces returns the interfaces declared at the top level of the file
+func (f *file) interfaces() []*ast.TypeSpec {
+	var specs []*ast.TypeSpec
+	for _, spec := range f.typeSpecs() {
+		if _, ok := spec.Type.(*ast.InterfaceType); ok {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// candidates returns the interfaces declared in the file, the closest to line
+// first
+func (f *file) candidates(line int) []Candidate {
+	var candidates []Candidate
+	for _, spec := range f.interfaces() {
+		candidates = append(candidates, Candidate{
+			Name:     spec.Name.Name,
+			Position: f.position(spec.Name.Pos()),
+		})
+	}
+	distance := func(c Candidate) int {
+		if c.Position.Line > line {
+			return c.Position.Line - line
+		}
+		return line - c.Position.Line
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distance(candidates[i]) < distance(candidates[j])
+	})
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+	return candidates
+}
@@ -0,0 +1,66 @@
+package mockgen
+
+import (
+	"errors"
+	"go/token"
+	"testing"
+)
+
+func TestTokenPos(t *testing.T) {
+	// The comment has a two byte rune that is one UTF-16 code unit, and a four
+	// byte rune that is a surrogate pair of two
+	src := "package p\n\n// é😀x\nvar s = 1"
+	f, err := openFile(token.NewFileSet(), "p.go", map[string][]byte{overlayKey("p.go"): []byte(src)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		pos  Position
+		// offset is the expected byte offset, -1 if the position is invalid
+		offset int
+	}{
+		{name: "offset", pos: Position{Offset: 11}, offset: 11},
+		{name: "offset zero", pos: Position{Offset: 0}, offset: 0},
+		{name: "offset at the end", pos: Position{Offset: len(src)}, offset: len(src)},
+		{name: "offset after the end", pos: Position{Offset: len(src) + 1}, offset: -1},
+		{name: "negative offset", pos: Position{Offset: -1}, offset: -1},
+
+		{name: "first column", pos: Position{X: 3, Y: 1}, offset: 11},
+		{name: "bytes before non-ASCII", pos: Position{X: 3, Y: 4}, offset: 14},
+		{name: "bytes after a two byte rune", pos: Position{X: 3, Y: 6}, offset: 16},
+		{name: "bytes after a four byte rune", pos: Position{X: 3, Y: 10}, offset: 20},
+		{name: "bytes at the end of the line", pos: Position{X: 3, Y: 11}, offset: 21},
+		{name: "bytes after the end of the line", pos: Position{X: 3, Y: 12}, offset: -1},
+
+		{name: "UTF-16 before non-ASCII", pos: Position{X: 3, Y: 4, Encoding: UTF16}, offset: 14},
+		{name: "UTF-16 after a two byte rune", pos: Position{X: 3, Y: 5, Encoding: UTF16}, offset: 16},
+		{name: "UTF-16 after a surrogate pair", pos: Position{X: 3, Y: 7, Encoding: UTF16}, offset: 20},
+		{name: "UTF-16 at the end of the line", pos: Position{X: 3, Y: 8, Encoding: UTF16}, offset: 21},
+		{name: "UTF-16 after the end of the line", pos: Position{X: 3, Y: 9, Encoding: UTF16}, offset: -1},
+		{name: "UTF-16 ASCII line", pos: Position{X: 1, Y: 9, Encoding: UTF16}, offset: 8},
+
+		{name: "empty line", pos: Position{X: 2, Y: 1}, offset: 10},
+		{name: "last line without newline", pos: Position{X: 4, Y: 10}, offset: len(src)},
+		{name: "line after the end", pos: Position{X: 5, Y: 1}, offset: -1},
+		{name: "column zero", pos: Position{X: 1, Y: 0}, offset: -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pos, err := f.tokenPos(test.pos)
+			if test.offset < 0 {
+				if !errors.Is(err, ErrInvalidPosition) {
+					t.Fatalf("got %v, want %v", err, ErrInvalidPosition)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if offset := f.fset.Position(pos).Offset; offset != test.offset {
+				t.Errorf("got offset %d, want %d", offset, test.offset)
+			}
+		})
+	}
+}
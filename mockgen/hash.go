@@ -0,0 +1,139 @@
+package mockgen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// hashVersion is part of every hash, it is changed when the generated code
+// changes to make all mocks out of date
+const hashVersion = "mockay 1"
+
+// header is the first line of generated files
+const header = "// Code generated by mockay. DO NOT EDIT.\n"
+
+// hashLine matches the line with the hash in the header of a generated file
+var hashLine = regexp.MustCompile(`(?m)^//mockay:hash ([0-9a-f]+)$`)
+
+// mockHash returns the hash of everything a generated mock depends on: the
+// method set and docs of the interface, the name of the mock, the package it
+// is generated in and how it is rendered. Positions are left out.
+func (f *Generator) mockHash(p *mockPlan) string {
+	iface := p.iface
+	rendering := "style " + p.style + "\nmock " + p.mock
+	if p.template != nil {
+		// The parse tree changes with the template, but not with comments
+		// and the formatting of actions. The name of the mock is not used.
+		rendering = "template " + p.template.Tree.Root.String()
+	}
+
+	h := sha256.New()
+	for _, part := range []string{
+		hashVersion,
+		"interface " + iface.Package.Path + "." + iface.Name,
+		"package " + p.pkg.Name + " " + p.pkg.Path,
+		rendering,
+		"build " + p.constraint,
+		iface.MethodSet(),
+		interfaceDocs(iface),
+	} {
+		h.Write([]byte(part + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// interfaceDocs returns the docs of an interface and its methods as text,
+// the methods sorted by name. Mocks repeat the docs.
+func interfaceDocs(iface *model.Interface) string {
+	lines := []string{"doc " + strconv.Quote(iface.Doc)}
+	for _, m := range iface.Methods {
+		lines = append(lines, "doc "+m.Name+" "+strconv.Quote(m.Doc))
+	}
+	sort.Strings(lines[1:])
+	return strings.Join(lines, "\n")
+}
+
+// mockLine matches the line in the header of a generated file naming the
+// interface that is mocked, followed by the name of the mock type unless it
+// was rendered from a template
+var mockLine = regexp.MustCompile(`(?m)^//mockay:mock (` + identifier + `)(?: (` + identifier + `))?$`)
+
+// identifier matches a Go identifier
+const identifier = `[\p{L}_][\p{L}\p{Nd}_]*`
+
+// withHeader returns the source of a generated file with a header marking it
+// as generated, which contains the hash and a line for each mock, with the
+// name of the interface optionally followed by the name of the mock type
+func withHeader(src []byte, hash string, mocks ...string) []byte {
+	head := header + "//mockay:hash " + hash + "\n"
+	for _, mock := range mocks {
+		head += "//mockay:mock " + mock + "\n"
+	}
+	return append([]byte(head+"\n"), src...)
+}
+
+// replaceHash replaces the hash in the header of src, if it has one. It is
+// used for files a mock has been merged into.
+func replaceHash(src []byte, hash string) []byte {
+	loc := hashLine.FindSubmatchIndex(headerOf(src))
+	if loc == nil {
+		return src
+	}
+	out := append([]byte{}, src[:loc[2]]...)
+	out = append(out, hash...)
+	return append(out, src[loc[3]:]...)
+}
+
+// FileHash returns the hash in the header of the generated file at path, it
+// is empty if the file does not exist or has no hash
+func FileHash(path string) string {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return headerHash(src)
+}
+
+// headerHash returns the hash in the header of the source of a generated
+// file, it is empty if there is none
+func headerHash(src []byte) string {
+	match := hashLine.FindSubmatch(headerOf(src))
+	if match == nil {
+		return ""
+	}
+	return string(match[1])
+}
+
+// headerOf returns the comments before the package clause of src
+func headerOf(src []byte) []byte {
+	end := 0
+	for end < len(src) {
+		next := bytes.IndexByte(src[end:], '\n')
+		if next < 0 {
+			next = len(src) - end
+		}
+		line := bytes.TrimSpace(src[end : end+next])
+		if len(line) > 0 && !bytes.HasPrefix(line, []byte("//")) {
+			break
+		}
+		end += next + 1
+	}
+	if end > len(src) {
+		end = len(src)
+	}
+	return src[:end]
+}
+
+// unchanged reports if the mock at output was generated with the hash in
+// its header
+func (f *Generator) unchanged(output, hash string) bool {
+	return FileHash(output) == hash
+}
@@ -0,0 +1,140 @@
+package mockgen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAllSkipsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n",
+		"store.go": "package store\n\ntype Store interface {\n\tGet() int\n}\n",
+	})
+	cache := t.TempDir()
+	generate := func() GeneratedFile {
+		t.Helper()
+		// Generators parse each file once
+		g := New(WithCache(cache))
+		files, err := g.GenerateAll(context.Background(), []string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("generated %d files, want 1", len(files))
+		}
+		if _, err := g.WriteFiles(files); err != nil {
+			t.Fatal(err)
+		}
+		return files[0]
+	}
+
+	first := generate()
+	old, err := os.ReadFile(first.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := generate(); !again.Unchanged {
+		t.Error("the mock was generated again without changes")
+	}
+
+	writeFiles(t, dir, map[string]string{
+		"store.go": "package store\n\ntype Store interface {\n\tGet() int\n\tPut(n int)\n}\n",
+	})
+	changed := generate()
+	if changed.Unchanged {
+		t.Fatal("the mock was not generated again after the interface changed")
+	}
+
+	// The previous mock is back, as after switching branches, while the
+	// cache has the hash of the current one
+	if err := os.WriteFile(first.Path, old, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if restored := generate(); restored.Unchanged {
+		t.Error("the restored mock was taken as up to date")
+	}
+	if hash := FileHash(first.Path); hash != changed.Hash {
+		t.Errorf("the mock has the hash %q, want %q", hash, changed.Hash)
+	}
+}
+
+func TestCheckDocChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n",
+		"store.go": "package store\n\n// Store stores\ntype Store interface {\n\t// Get gets\n\tGet() int\n}\n",
+	})
+	g := New()
+	files, err := g.GenerateAll(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.WriteFiles(files); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []string{
+		"package store\n\n// Store stores\ntype Store interface {\n\t// Get gets a number\n\tGet() int\n}\n",
+		"package store\n\n// Store stores numbers\ntype Store interface {\n\t// Get gets\n\tGet() int\n}\n",
+	} {
+		writeFiles(t, dir, map[string]string{"store.go": src})
+		stale, err := New().Check(context.Background(), []string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stale) != 1 {
+			t.Errorf("the mock is up to date after changing the docs to:\n%s", src)
+		}
+	}
+}
+
+func TestGenerateAllCachedPackage(t *testing.T) {
+	dir := t.TempDir()
+	src := "package store\n\ntype Store interface {\n\tGet() int\n}\n"
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n",
+		"store.go": src,
+	})
+	cache := t.TempDir()
+	g := New(WithCache(cache))
+	files, err := g.GenerateAll(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.WriteFiles(files); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another interface in a file with the stamp of the cached one shows
+	// that the package is not parsed again
+	path := filepath.Join(dir, "store.go")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(src, "Store", "Other", 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	files, err = New(WithCache(cache)).GenerateAll(context.Background(), []string{dir})
+	if err != nil || len(files) != 1 || !files[0].Unchanged || files[0].Interfaces[0] != "Store" {
+		t.Errorf("got %v, %v, want the cached mock of Store", files, err)
+	}
+	if files, err := New().GenerateAll(context.Background(), []string{dir}); err != nil || len(files) != 1 || files[0].Interfaces[0] != "Other" {
+		t.Errorf("got %v, %v without a cache, want a mock of Other", files, err)
+	}
+
+	// The cache is not used once the mock is modified
+	if err := os.WriteFile(files[0].Path, []byte("package mock\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if files, err := New(WithCache(cache)).GenerateAll(context.Background(), []string{dir}); err != nil || len(files) != 1 || files[0].Interfaces[0] != "Other" {
+		t.Errorf("got %v, %v after modifying the mock, want a mock of Other", files, err)
+	}
+}
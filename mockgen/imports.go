@@ -0,0 +1,112 @@
+package mockgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// Imports keeps the imports of a generated file and the names the imported
+// packages are referred to with
+type Imports struct {
+	// local is the package the file is generated in, types from it are not
+	// qualified
+	local *model.Import
+	names map[string]string
+	used  map[string]bool
+}
+
+func newImports(local *model.Import) *Imports {
+	return &Imports{
+		local: local,
+		names: map[string]string{},
+		used:  map[string]bool{},
+	}
+}
+
+// Add imports a package and returns the name it is referred to with, which
+// differs from the name of the package if that name is already taken
+func (s *Imports) Add(imp model.Import) string {
+	if name, ok := s.names[imp.Path]; ok {
+		return name
+	}
+	name := imp.Name
+	for i := 2; s.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", imp.Name, i)
+	}
+	s.used[name] = true
+	if imp.Path != "" {
+		s.names[imp.Path] = name
+	}
+	return name
+}
+
+// IsLocal reports if imp is the package the file is generated in
+func (s *Imports) IsLocal(imp model.Import) bool {
+	return s.local != nil && *s.local == imp
+}
+
+// Used reports if name is the name of an imported package, which parameters
+// and variables may not shadow
+func (s *Imports) Used(name string) bool {
+	return s.used[name]
+}
+
+// TypeExpr creates the expression of a type where packages are referred to
+// with the names they are imported with, importing them if needed
+func (s *Imports) TypeExpr(ref model.TypeRef) ast.Expr {
+	expr, err := parser.ParseExpr(ref.Expr)
+	if err != nil {
+		return ident(ref.Expr)
+	}
+	return mapTypeExpr(expr, func(e ast.Expr) ast.Expr {
+		sel, ok := e.(*ast.SelectorExpr)
+		if !ok {
+			return nil
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		for _, imp := range ref.Imports {
+			if imp.Name != x.Name {
+				continue
+			}
+			if s.IsLocal(imp) {
+				return ident(sel.Sel.Name)
+			}
+			return selector(ident(s.Add(imp)), sel.Sel.Name)
+		}
+		return nil
+	})
+}
+
+// decl returns the import declaration, sorted by path
+func (s *Imports) decl() *ast.GenDecl {
+	paths := make([]string, 0, len(s.names))
+	for p := range s.names {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	decl := &ast.GenDecl{Tok: token.IMPORT}
+	for _, p := range paths {
+		spec := &ast.ImportSpec{
+			Path: &ast.BasicLit{
+				Kind:  token.STRING,
+				Value: strconv.Quote(p),
+			},
+		}
+		if name := s.names[p]; name != path.Base(p) {
+			spec.Name = ident(name)
+		}
+		decl.Specs = append(decl.Specs, spec)
+	}
+	return decl
+}
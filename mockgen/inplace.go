@@ -0,0 +1,439 @@
+package mockgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// helperSuffixes are the suffixes of all methods generated for each interface method
+var helperSuffixes = []string{"", "CallCount", "ArgsForCall", "Returns", "ReturnsOnCall"}
+
+// mergeFile returns the content of the file at path with the generated mock,
+// called name, replacing any mock previously generated in it. The generated file is
+// returned as it is if path does not exist, which is reported by merged being
+// false.
+func (f *Generator) mergeFile(path string, generated *ast.File, name string) (src []byte, merged bool, err error) {
+	src, ok := f.overlay[overlayKey(path)]
+	if !ok {
+		src, err = os.ReadFile(path)
+		if os.IsNotExist(err) {
+			src, err = printFile(generated)
+			return src, false, err
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	f.logger.Info("merging mock", "path", path)
+	src, err = mergeInto(src, generated, name)
+	return src, err == nil, err
+}
+
+// mergeInto replaces the previously generated declarations of the mock called
+// name in src with the declarations of the generated file. Everything else in
+// src is left as it is. If src does not contain the mock, it is appended.
+func mergeInto(src []byte, generated *ast.File, name string) ([]byte, error) {
+	fset := token.NewFileSet()
+	target, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var spans []span
+	var replaced []ast.Node
+	methods := map[string]bool{}
+	for _, decl := range target.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.TypeSpec)
+			if spec.Name.Name != name {
+				continue
+			}
+			if len(gen.Specs) > 1 {
+				return nil, fmt.Errorf("%s is declared in a grouped type declaration and can not be replaced", name)
+			}
+			spans = append(spans, declSpan(fset, src, gen))
+			replaced = append(replaced, gen)
+			for _, m := range mockedMethods(spec) {
+				for _, suffix := range helperSuffixes {
+					methods[m+suffix] = true
+				}
+			}
+		}
+	}
+	for _, decl := range target.Decls {
+		fun, ok := decl.(*ast.FuncDecl)
+		if ok && receiverName(fun) == name && methods[fun.Name.Name] {
+			spans = append(spans, declSpan(fset, src, fun))
+			replaced = append(replaced, fun)
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	var decls []ast.Decl
+	var imports []*ast.ImportSpec
+	for _, decl := range generated.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			for _, spec := range gen.Specs {
+				imports = append(imports, spec.(*ast.ImportSpec))
+			}
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	mock, err := printDecls(decls)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if len(spans) == 0 {
+		out.Write(src)
+		out.WriteString("\n")
+		out.Write(mock)
+	} else {
+		last := 0
+		for i, s := range spans {
+			out.Write(src[last:s.start])
+			if i == 0 {
+				out.Write(mock)
+			}
+			last = s.end
+		}
+		out.Write(src[last:])
+	}
+
+	// Packages only the replaced mock referred to may no longer be used
+	merged, err := removeUnusedImports(out.Bytes(), packageRefs(replaced...))
+	if err == nil {
+		merged, err = addImports(merged, imports)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(merged)
+}
+
+// span is a range of bytes in a source file
+type span struct {
+	start, end int
+}
+
+// declSpan returns the full lines covered by a declaration and its doc comment
+func declSpan(fset *token.FileSet, src []byte, decl ast.Decl) span {
+	pos := decl.Pos()
+	switch decl := decl.(type) {
+	case *ast.GenDecl:
+		if decl.Doc != nil {
+			pos = decl.Doc.Pos()
+		}
+	case *ast.FuncDecl:
+		if decl.Doc != nil {
+			pos = decl.Doc.Pos()
+		}
+	}
+	start := fset.Position(pos).Offset
+	start = bytes.LastIndexByte(src[:start], '\n') + 1
+
+	end := fset.Position(decl.End()).Offset
+	if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+		end += i + 1
+	} else {
+		end = len(src)
+	}
+	return span{start: start, end: end}
+}
+
+// mockedMethods returns the names of the methods a previously generated mock
+// struct was generated with, based on its Func fields
+func mockedMethods(spec *ast.TypeSpec) []string {
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, f := range st.Fields.List {
+		for _, n := range f.Names {
+			if strings.HasSuffix(n.Name, "Func") {
+				names = append(names, strings.TrimSuffix(n.Name, "Func"))
+			}
+		}
+	}
+	return names
+}
+
+func receiverName(fun *ast.FuncDecl) string {
+	if fun.Recv == nil || len(fun.Recv.List) == 0 {
+		return ""
+	}
+	typ := fun.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	// Receivers of generic types have type parameters, e.g. *Mocked[K, V]
+	switch index := typ.(type) {
+	case *ast.IndexExpr:
+		typ = index.X
+	case *ast.IndexListExpr:
+		typ = index.X
+	}
+	if id, ok := typ.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// printDecls formats declarations without any package clause
+func printDecls(decls []ast.Decl) ([]byte, error) {
+	src, err := printFile(&ast.File{
+		Name:  ident("p"),
+		Decls: decls,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(src, []byte("package p\n\n")), nil
+}
+
+// packageRefs returns the names of the packages referred to in the nodes,
+// the identifiers qualifying selectors that are not declared in the file
+func packageRefs(nodes ...ast.Node) map[string]bool {
+	refs := map[string]bool{}
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+					refs[x.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return refs
+}
+
+// importName returns the name a package is imported with. Without a name in
+// the import, it is assumed from the path as goimports does, by the last
+// element that is not a major version and without a go- prefix, which may
+// differ from the name of the package.
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	p, err := strconv.Unquote(imp.Path.Value)
+	if err != nil {
+		return ""
+	}
+	base := path.Base(p)
+	if _, err := strconv.Atoi(strings.TrimPrefix(base, "v")); err == nil && strings.HasPrefix(base, "v") && path.Dir(p) != "." {
+		base = path.Base(path.Dir(p))
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// removeUnusedImports removes the imports of src with the candidate names
+// that nothing in src refers to any more. Only the names of packages a
+// replaced mock referred to are candidates, as the names of other imports
+// may not be known from their paths.
+func removeUnusedImports(src []byte, candidates map[string]bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	used := packageRefs(file)
+
+	var spans []span
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		var unused []span
+		for _, spec := range gen.Specs {
+			name := importName(spec.(*ast.ImportSpec))
+			if candidates[name] && !used[name] {
+				unused = append(unused, specSpan(fset, src, spec.(*ast.ImportSpec)))
+			}
+		}
+		if len(unused) == len(gen.Specs) && len(unused) > 0 {
+			spans = append(spans, declSpan(fset, src, gen))
+		} else {
+			spans = append(spans, unused...)
+		}
+	}
+	if len(spans) == 0 {
+		return src, nil
+	}
+
+	var out bytes.Buffer
+	last := 0
+	for _, s := range spans {
+		out.Write(src[last:s.start])
+		last = s.end
+	}
+	out.Write(src[last:])
+	return out.Bytes(), nil
+}
+
+// specSpan returns the full lines covered by an import spec and its doc
+// comment
+func specSpan(fset *token.FileSet, src []byte, spec *ast.ImportSpec) span {
+	pos := spec.Pos()
+	if spec.Doc != nil {
+		pos = spec.Doc.Pos()
+	}
+	start := fset.Position(pos).Offset
+	start = bytes.LastIndexByte(src[:start], '\n') + 1
+	end := fset.Position(spec.End()).Offset
+	if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+		end += i + 1
+	}
+	return span{start: start, end: end}
+}
+
+// addImports adds the imports that does not already exist in src. A package
+// imported with another name than the one in imports is imported again with
+// that name, while an import without a name is taken to have the right one.
+func addImports(src []byte, imports []*ast.ImportSpec) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]bool{}
+	for _, imp := range file.Imports {
+		if imp.Name == nil {
+			existing[imp.Path.Value] = true
+		} else {
+			existing[imp.Name.Name+" "+imp.Path.Value] = true
+		}
+	}
+	var missing []string
+	for _, imp := range imports {
+		// Generated imports are only named when the name differs from the
+		// last element of the path
+		name := path.Base(strings.Trim(imp.Path.Value, `"`))
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if existing[imp.Path.Value] || existing[name+" "+imp.Path.Value] {
+			continue
+		}
+		line := imp.Path.Value
+		if imp.Name != nil {
+			line = imp.Name.Name + " " + line
+		}
+		missing = append(missing, line)
+	}
+	if len(missing) == 0 {
+		return src, nil
+	}
+
+	// Add the imports to the last import declaration, grouping it if needed,
+	// or add a new declaration after the package clause
+	var last *ast.GenDecl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			last = gen
+		}
+	}
+	var insert string
+	var start, end int
+	switch {
+	case last != nil && last.Lparen.IsValid():
+		start = fset.Position(last.Rparen).Offset
+		end = start
+		insert = "\t" + strings.Join(missing, "\n\t") + "\n"
+	case last != nil:
+		start = fset.Position(last.Pos()).Offset
+		end = fset.Position(last.End()).Offset
+		spec := string(src[fset.Position(last.Specs[0].Pos()).Offset:end])
+		insert = "import (\n\t" + spec + "\n\t" + strings.Join(missing, "\n\t") + "\n)"
+	case len(missing) == 1:
+		start = fset.Position(file.Name.End()).Offset
+		end = start
+		insert = "\n\nimport " + missing[0]
+	default:
+		start = fset.Position(file.Name.End()).Offset
+		end = start
+		insert = "\n\nimport (\n\t" + strings.Join(missing, "\n\t") + "\n)"
+	}
+
+	var out bytes.Buffer
+	out.Write(src[:start])
+	out.WriteString(insert)
+	out.Write(src[end:])
+	return out.Bytes(), nil
+}
+
+// joinSources joins the generated sources of mocks into one file of the
+// package called name, importing the packages imported by any of them. It
+// is an error if they import different packages with the same name.
+func joinSources(name string, srcs [][]byte) ([]byte, error) {
+	paths := map[string]string{}
+	var specs []string
+	var out bytes.Buffer
+	for _, src := range srcs {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+		if err != nil {
+			return nil, err
+		}
+		end := fset.Position(file.Name.End()).Offset
+		for _, decl := range file.Decls {
+			end = fset.Position(decl.End()).Offset
+		}
+		for _, imp := range file.Imports {
+			spec := imp.Path.Value
+			imported := path.Base(strings.Trim(imp.Path.Value, `"`))
+			if imp.Name != nil {
+				spec = imp.Name.Name + " " + spec
+				imported = imp.Name.Name
+			}
+			if imported == "_" {
+				imported += imp.Path.Value
+			}
+			if other, ok := paths[imported]; ok {
+				if other != imp.Path.Value {
+					return nil, fmt.Errorf("%s and %s are both imported as %s", other, imp.Path.Value, imported)
+				}
+				continue
+			}
+			paths[imported] = imp.Path.Value
+			specs = append(specs, spec)
+		}
+		out.WriteString("\n")
+		out.Write(bytes.TrimSpace(src[end:]))
+		out.WriteString("\n")
+	}
+
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i][strings.LastIndexByte(specs[i], ' ')+1:] < specs[j][strings.LastIndexByte(specs[j], ' ')+1:]
+	})
+	head := "package " + name + "\n"
+	if len(specs) > 0 {
+		head += "\nimport (\n\t" + strings.Join(specs, "\n\t") + "\n)\n"
+	}
+	return format.Source(append([]byte(head), out.Bytes()...))
+}
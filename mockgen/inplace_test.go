@@ -0,0 +1,83 @@
+package mockgen
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateMergeIntoNewFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"store_linux.go": "package store\n\ntype Store interface {\n\tGet() int\n}\n",
+		"existing.go":    "// Package store stores\npackage store\n\nconst keep = 1\n",
+	})
+
+	tests := []struct {
+		name      string
+		output    string
+		generated bool
+	}{
+		{name: "new file", output: "mock/store.go", generated: true},
+		{name: "existing file", output: "existing.go"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := New().GenerateFiles(context.Background(), Request{
+				Path:   filepath.Join(dir, "store_linux.go"),
+				Name:   "Store",
+				Output: filepath.Join(dir, test.output),
+				Merge:  true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			src := string(files[0].Source)
+			hasHeader := strings.HasPrefix(src, header) && headerHash(files[0].Source) == files[0].Hash
+			if hasHeader != test.generated || strings.Contains(src, "//go:build linux\n") != test.generated {
+				t.Errorf("got header %t, want %t:\n%s", hasHeader, test.generated, src)
+			}
+			if !test.generated && !strings.Contains(src, "const keep = 1") {
+				t.Errorf("the content of the file was not kept:\n%s", src)
+			}
+		})
+	}
+}
+
+func TestMergeRemovesUnusedImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n",
+		"store.go": "package store\n\nimport \"io\"\n\ntype Store interface {\n\tGet() int\n\tRead(r io.Reader)\n}\n",
+		"store_mock_test.go": "package store\n\nimport \"strings\"\n\n" +
+			"// helper is kept with the mock\nfunc helper() string {\n\treturn strings.ToUpper(\"a\")\n}\n",
+	})
+	output := filepath.Join(dir, "store_mock_test.go")
+	merge := func() string {
+		t.Helper()
+		// Generators parse each file once
+		files, err := New(WithVerify(), WithLayout(Layout{Package: TestPackage})).GenerateFiles(context.Background(), Request{
+			Path:   filepath.Join(dir, "store.go"),
+			Name:   "Store",
+			Output: output,
+			Merge:  true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, dir, map[string]string{"store_mock_test.go": string(files[0].Source)})
+		return string(files[0].Source)
+	}
+
+	if src := merge(); !strings.Contains(src, "\"io\"") {
+		t.Fatalf("the mock does not import io:\n%s", src)
+	}
+	writeFiles(t, dir, map[string]string{
+		"store.go": "package store\n\ntype Store interface {\n\tGet() int\n}\n",
+	})
+	src := merge()
+	if strings.Contains(src, "\"io\"") || !strings.Contains(src, "\"strings\"") {
+		t.Errorf("the imports are not those of the mock and the helper:\n%s", src)
+	}
+}
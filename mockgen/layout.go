@@ -0,0 +1,120 @@
+package mockgen
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// PackageMode is the kind of package mocks are generated in
+type PackageMode int
+
+const (
+	// MockPackage puts mocks in a package of their own, in Layout.Dir
+	MockPackage PackageMode = iota
+	// TestPackage puts mocks in the package of the interface, in _test.go
+	// files next to it
+	TestPackage
+	// ExternalTestPackage puts mocks in the external test package of the
+	// package of the interface, the one named with the suffix _test, in
+	// _test.go files next to it
+	ExternalTestPackage
+)
+
+// Layout decides where mocks are written to, when the path is not given in
+// the request
+type Layout struct {
+	Package PackageMode
+	// Dir is the directory of the mocks with MockPackage, relative to the
+	// directory of the file the mock is requested for, usually the one
+	// declaring the interface, unless it is absolute. It is mock if not
+	// set. The name of the package is the name of the directory.
+	Dir string
+	// FileName is the template of the name of the file of a mock, executed
+	// with FileNameData. The file is named after the interface in snake_case
+	// if not set, with the suffix _mock_test.go in test packages.
+	FileName *template.Template
+}
+
+// FileNameData is what the file name template of a Layout is executed with
+type FileNameData struct {
+	// Interface is the name of the interface
+	Interface string
+	// Package is the name of the package of the interface
+	Package string
+}
+
+// WithLayout sets where mocks are written to when no output path is given,
+// the path returned by MockPath is used if not set
+func WithLayout(layout Layout) Option {
+	return func(f *Generator) { f.layout = layout }
+}
+
+// ParseFileName parses the template of the file names of a Layout, e.g.
+// {{.Interface | snake}}_mock.go. The functions of TemplateFuncs are
+// available in it.
+func ParseFileName(text string) (*template.Template, error) {
+	return template.New("filename").Funcs(TemplateFuncs()).Option("missingkey=error").Parse(text)
+}
+
+// outputPath returns the path of the mock of an interface requested for a file
+// in dir, as given by the layout of the generator
+func (f *Generator) outputPath(iface *model.Interface, dir string) (string, error) {
+	l := f.layout
+	if l.Package == MockPackage {
+		mockDir := l.Dir
+		if mockDir == "" {
+			mockDir = "mock"
+		}
+		if !filepath.IsAbs(mockDir) {
+			mockDir = filepath.Join(dir, mockDir)
+		}
+		dir = mockDir
+	}
+
+	name := snakeCase(iface.Name) + ".go"
+	if l.Package != MockPackage {
+		name = snakeCase(iface.Name) + "_mock_test.go"
+	}
+	if l.FileName != nil {
+		var buf bytes.Buffer
+		err := l.FileName.Execute(&buf, FileNameData{Interface: iface.Name, Package: iface.Package.Name})
+		if err != nil {
+			return "", fmt.Errorf("could not name the mock of %s: %w", iface.Name, err)
+		}
+		name = buf.String()
+	}
+
+	switch {
+	case name == "" || strings.ContainsAny(name, `/\`):
+		return "", fmt.Errorf("invalid file name %q of the mock of %s, it must not be empty or contain directories", name, iface.Name)
+	case !strings.HasSuffix(name, ".go"):
+		return "", fmt.Errorf("invalid file name %q of the mock of %s, it must end with .go", name, iface.Name)
+	case l.Package != MockPackage && !strings.HasSuffix(name, "_test.go"):
+		return "", fmt.Errorf("invalid file name %q of the mock of %s, mocks in test packages must be in _test.go files", name, iface.Name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// outputPackage returns the package of a mock written to output, the package
+// in the directory of output. With ExternalTestPackage, test files are in the
+// external test package of it. Import paths are found from go.mod.
+func (f *Generator) outputPackage(output string) model.Package {
+	dir := filepath.Dir(overlayKey(output))
+	pkg := model.Package{
+		Name: f.loader.packageName(dir),
+		Path: f.loader.importPath(dir),
+		Dir:  dir,
+	}
+	if f.layout.Package == ExternalTestPackage && strings.HasSuffix(output, "_test.go") {
+		pkg.Name += "_test"
+		if pkg.Path != "" {
+			pkg.Path += "_test"
+		}
+	}
+	return pkg
+}
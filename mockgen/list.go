@@ -0,0 +1,207 @@
+package mockgen
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// ListedInterface is an interface found by List
+type ListedInterface struct {
+	Name     string         `json:"name"`
+	Package  model.Package  `json:"package"`
+	Position model.Position `json:"position"`
+	// Methods is the number of methods, including those of embedded interfaces
+	Methods  int  `json:"methods"`
+	Exported bool `json:"exported"`
+	// Mock is the path of a file with a mock of the interface generated by
+	// mockay, empty if no such file is found in the module
+	Mock string `json:"mock,omitempty"`
+}
+
+// List returns the interfaces declared in a file, or in the files of a
+// directory. Interfaces that can not be mocked, like constraints with type
+// sets, are left out.
+func (f *Generator) List(ctx context.Context, path string) ([]ListedInterface, error) {
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	paths := []string{path}
+	if err == nil && info.IsDir() {
+		paths, err = f.loader.goFiles(overlayKey(path), true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var mocks []existingMock
+	scanned := false
+	var listed []ListedInterface
+	for _, p := range paths {
+		file, err := f.loader.openFile(p)
+		var parseErr *ParseError
+		if len(paths) > 1 && errors.As(err, &parseErr) {
+			f.logger.Warn("skipping file that can not be parsed", "file", p, "err", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range file.interfaces() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			iface, err := f.loader.parseInterface(file, spec)
+			if err != nil {
+				f.logger.Warn("skipping interface", "name", spec.Name.Name, "err", err)
+				continue
+			}
+
+			if !scanned {
+				mocks = f.findMocks(iface.Package.Dir)
+				scanned = true
+			}
+			l := ListedInterface{
+				Name:     iface.Name,
+				Package:  iface.Package,
+				Position: iface.Position,
+				Methods:  len(iface.Methods),
+				Exported: ast.IsExported(iface.Name),
+			}
+			for _, m := range mocks {
+				if m.mocks(iface) {
+					l.Mock = m.path
+					break
+				}
+			}
+			listed = append(listed, l)
+		}
+	}
+	return listed, nil
+}
+
+// mockDoc matches the doc comment of mock structs generated by the default
+// style, which finds mocks merged into files without a header
+var mockDoc = regexp.MustCompile(`^` + identifier + ` is a mock implementation of (` + identifier + `)\n`)
+
+// existingMock is a mock generated by mockay
+type existingMock struct {
+	path string
+	// name is the name of the mock type
+	name      string
+	iface     string
+	dir       string
+	importing map[string]bool
+}
+
+// mocks reports if m could be a mock of the interface. The doc comment of the
+// mock only contains the name of the interface, the mock must also be in the
+// package of the interface, below it or import it.
+func (m existingMock) mocks(iface *model.Interface) bool {
+	if m.iface != iface.Name {
+		return false
+	}
+	rel, err := filepath.Rel(iface.Package.Dir, m.dir)
+	if err == nil && !strings.HasPrefix(rel, "..") {
+		return true
+	}
+	return iface.Package.Path != "" && m.importing[iface.Package.Path]
+}
+
+// findMocks finds the mocks generated by mockay in the module containing dir,
+// or in dir if it is not in a module
+func (f *Generator) findMocks(dir string) []existingMock {
+	root, _ := findModule(dir)
+	if root == "" {
+		root = dir
+	}
+
+	var mocks []existingMock
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		mocks = append(mocks, parseMocks(path)...)
+		return nil
+	})
+	return mocks
+}
+
+// skipDir reports if a directory is left out when walking a module, as done by
+// the go command for ./...
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// parseMocks returns the mocks generated by mockay in the file at path. Mocks
+// are named in the header of generated files, mocks of the default style
+// merged into other files are found by their doc comments.
+func parseMocks(path string) []existingMock {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	generated := mockLine.FindAllSubmatch(headerOf(src), -1)
+	if len(generated) == 0 && !strings.Contains(string(src), " is a mock implementation of ") {
+		return nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	importing := map[string]bool{}
+	for _, imp := range file.Imports {
+		if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+			importing[p] = true
+		}
+	}
+	mock := func(iface, name string) existingMock {
+		return existingMock{
+			path:      path,
+			name:      name,
+			iface:     iface,
+			dir:       filepath.Dir(path),
+			importing: importing,
+		}
+	}
+
+	var mocks []existingMock
+	named := map[string]bool{}
+	for _, match := range generated {
+		mocks = append(mocks, mock(string(match[1]), string(match[2])))
+		named[string(match[1])] = true
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE || len(gen.Specs) != 1 {
+			continue
+		}
+		match := mockDoc.FindStringSubmatch(gen.Doc.Text())
+		if match == nil || named[match[1]] {
+			continue
+		}
+		mocks = append(mocks, mock(match[1], gen.Specs[0].(*ast.TypeSpec).Name.Name))
+	}
+	return mocks
+}
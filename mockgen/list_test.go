@@ -0,0 +1,67 @@
+package mockgen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+)
+
+// writeFiles writes the files, by path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListFindsMocks(t *testing.T) {
+	tmpl := template.Must(template.New("mock").Funcs(TemplateFuncs()).Parse(
+		"package mock\n\ntype Fake{{.Name}} struct{}\n"))
+
+	tests := []struct {
+		name    string
+		options []Option
+		iface   string
+	}{
+		{name: "default style", iface: "Store"},
+		{name: "non-ASCII name", iface: "Ü"},
+		{name: "template", options: []Option{WithTemplate(tmpl)}, iface: "Store"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"go.mod":   "module example.com/m\n",
+				"store.go": "package store\n\ntype " + test.iface + " interface {\n\tGet() int\n}\n",
+			})
+
+			g := New(test.options...)
+			files, err := g.GenerateAll(context.Background(), []string{dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := g.WriteFiles(files); err != nil {
+				t.Fatal(err)
+			}
+
+			listed, err := New().List(context.Background(), filepath.Join(dir, "store.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(listed) != 1 || len(files) != 1 {
+				t.Fatalf("listed %d interfaces and generated %d mocks, expected one", len(listed), len(files))
+			}
+			if listed[0].Mock != files[0].Path {
+				t.Errorf("got mock %q, want %q", listed[0].Mock, files[0].Path)
+			}
+		})
+	}
+}
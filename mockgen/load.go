@@ -0,0 +1,300 @@
+package mockgen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// loader parses files and packages, and keeps them so that each file is only
+// parsed once. It is safe for concurrent use, the syntax trees it returns are
+// shared and must not be modified.
+type loader struct {
+	logger  Logger
+	fset    *token.FileSet
+	overlay map[string][]byte
+
+	// mu guards the maps below. It is not held while parsing, a file that is
+	// opened by two goroutines at once may be parsed twice, but only the
+	// first result is kept.
+	mu    sync.Mutex
+	files map[string]*file
+	pkgs  map[string]*pkg
+	paths map[string]string
+}
+
+// pkg is the parsed files of a package in a directory
+type pkg struct {
+	name  string
+	dir   string
+	files []*file
+}
+
+func newLoader(overlay map[string][]byte, logger Logger) *loader {
+	return &loader{
+		logger:  logger,
+		fset:    token.NewFileSet(),
+		overlay: overlay,
+		files:   map[string]*file{},
+		pkgs:    map[string]*pkg{},
+		paths:   map[string]string{},
+	}
+}
+
+func (l *loader) openFile(path string) (*file, error) {
+	key := overlayKey(path)
+	l.mu.Lock()
+	f, ok := l.files[key]
+	l.mu.Unlock()
+	if ok {
+		return f, nil
+	}
+	f, err := openFile(l.fset, path, l.overlay)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cached, ok := l.files[key]; ok {
+		return cached, nil
+	}
+	l.files[key] = f
+	return f, nil
+}
+
+// forget drops the parsed file at path and the packages of its directory, so
+// that they are parsed again when the file has changed
+func (l *loader) forget(path string) {
+	key := overlayKey(path)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.files, key)
+	for k, p := range l.pkgs {
+		if p.dir == filepath.Dir(key) {
+			delete(l.pkgs, k)
+		}
+	}
+}
+
+// loadDir parses the package named name in dir. Test files are only included
+// if includeTests is set.
+func (l *loader) loadDir(dir, name string, includeTests bool) (*pkg, error) {
+	dir = overlayKey(dir)
+	key := fmt.Sprintf("%s:%s:%t", dir, name, includeTests)
+	l.mu.Lock()
+	p, ok := l.pkgs[key]
+	l.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+
+	sorted, err := l.goFiles(dir, includeTests)
+	if err != nil {
+		return nil, err
+	}
+
+	p = &pkg{
+		name: name,
+		dir:  dir,
+	}
+	for _, path := range sorted {
+		f, err := l.openFile(path)
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			// Other files of the package may be broken while being edited
+			l.logger.Warn("skipping file that can not be parsed", "file", path, "err", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.name == "" {
+			p.name = f.astFile.Name.Name
+		}
+		if f.astFile.Name.Name == p.name {
+			p.files = append(p.files, f)
+		}
+	}
+	l.logger.Debug("loaded package", "dir", dir, "name", p.name, "files", len(p.files))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cached, ok := l.pkgs[key]; ok {
+		return cached, nil
+	}
+	l.pkgs[key] = p
+	return p, nil
+}
+
+// goFiles returns the sorted paths of the Go files in dir that match the
+// build constraints, including files only in the overlay
+func (l *loader) goFiles(dir string, includeTests bool) ([]string, error) {
+	paths := map[string]bool{}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			paths[filepath.Join(dir, e.Name())] = true
+		}
+	}
+	for path := range l.overlay {
+		if filepath.Dir(path) == dir {
+			paths[path] = true
+		}
+	}
+
+	var sorted []string
+	for path := range paths {
+		base := filepath.Base(path)
+		if !strings.HasSuffix(base, ".go") || (!includeTests && strings.HasSuffix(base, "_test.go")) {
+			continue
+		}
+		if match, err := build.Default.MatchFile(dir, base); err == nil && !match {
+			if _, ok := l.overlay[path]; !ok {
+				continue
+			}
+		}
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// importPackage loads the package imported with the import path from a file in srcDir
+func (l *loader) importPackage(importPath, srcDir string) (*pkg, error) {
+	// The go command used to find packages in modules is run in ctx.Dir
+	ctx := build.Default
+	ctx.Dir = srcDir
+	bp, err := ctx.Import(importPath, srcDir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not find package %s: %w", importPath, err)
+	}
+	l.logger.Debug("imported package", "path", importPath, "dir", bp.Dir)
+	return l.loadDir(bp.Dir, bp.Name, false)
+}
+
+// importPath returns the import path of the package in dir, which does not
+// need to exist yet. The path is found from the closest go.mod, or from
+// GOPATH when there is none. An empty path is returned if neither is found.
+func (l *loader) importPath(dir string) string {
+	dir = overlayKey(dir)
+	l.mu.Lock()
+	p, ok := l.paths[dir]
+	l.mu.Unlock()
+	if ok {
+		return p
+	}
+
+	importPath := ""
+	if root, module := findModule(dir); module != "" {
+		rel, err := filepath.Rel(root, dir)
+		if err == nil {
+			importPath = path.Join(module, filepath.ToSlash(rel))
+		}
+	}
+	if importPath == "" {
+		for _, root := range build.Default.SrcDirs() {
+			rel, err := filepath.Rel(root, dir)
+			if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+				importPath = filepath.ToSlash(rel)
+				break
+			}
+		}
+	}
+
+	l.mu.Lock()
+	l.paths[dir] = importPath
+	l.mu.Unlock()
+	return importPath
+}
+
+// packageName returns the name of the package in dir, or a name made from the
+// last element of dir if there is no package in it yet
+func (l *loader) packageName(dir string) string {
+	if p, err := l.loadDir(dir, "", false); err == nil && p.name != "" {
+		return p.name
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return -1
+	}, filepath.Base(dir))
+	if name == "" || name[0] >= '0' && name[0] <= '9' || token.IsKeyword(name) {
+		return "mock"
+	}
+	return name
+}
+
+// findModule returns the directory and path of the module containing dir,
+// both are empty if there is no go.mod in dir or any of its parents
+func findModule(dir string) (string, string) {
+	for root := dir; ; root = filepath.Dir(root) {
+		if module := modulePath(filepath.Join(root, "go.mod")); module != "" {
+			return root, module
+		}
+		if filepath.Dir(root) == root {
+			return "", ""
+		}
+	}
+}
+
+// modulePath reads the module path from a go.mod file, it is empty if the
+// file does not exist
+func modulePath(gomod string) string {
+	content, err := os.ReadFile(gomod)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			if module, err := strconv.Unquote(fields[1]); err == nil {
+				return module
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// lookupType finds the type spec declared with name in the package
+func (p *pkg) lookupType(name string) (*file, *ast.TypeSpec) {
+	for _, f := range p.files {
+		if spec := f.lookupType(name); spec != nil {
+			return f, spec
+		}
+	}
+	return nil, nil
+}
+
+// lookupType finds the type spec declared with name at the top level of the
+// file
+func (f *file) lookupType(name string) *ast.TypeSpec {
+	for _, decl := range f.astFile.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.TypeSpec)
+			if spec.Name.Name == name {
+				return spec
+			}
+		}
+	}
+	return nil
+}
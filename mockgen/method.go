@@ -0,0 +1,364 @@
+package mockgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lindell/mockay/astcopy"
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// reserved are names used inside the generated methods that parameters and
+// named results may not shadow
+var reserved = map[string]bool{
+	"m":              true,
+	"stub":           true,
+	"ret":            true,
+	"specificReturn": true,
+	"returns":        true,
+	"len":            true,
+	"append":         true,
+}
+
+// variable is a single parameter or result of a method
+type variable struct {
+	name     string
+	typ      ast.Expr
+	variadic bool
+}
+
+// mockMethod generates everything needed to mock a single interface method
+type mockMethod struct {
+	name    string
+	recv    ast.Expr
+	iface   string
+	doc     string
+	params  []variable
+	results []variable
+}
+
+func newMockMethod(recv ast.Expr, interfaceName string, method model.Method, imports *Imports) *mockMethod {
+	m := &mockMethod{
+		name:  method.Name,
+		recv:  recv,
+		iface: interfaceName,
+		doc:   method.Doc,
+	}
+	// Parameters without a usable name are given one, as are parameters that
+	// would shadow names used in the generated methods
+	for i, p := range method.Params {
+		name := p.Name
+		if name == "" || name == "_" || reserved[name] || imports.Used(name) {
+			name = fmt.Sprintf("var%d", i+1)
+		}
+		m.params = append(m.params, variable{
+			name:     name,
+			typ:      imports.TypeExpr(p.Type),
+			variadic: method.Variadic && i == len(method.Params)-1,
+		})
+	}
+	// Named results are renamed the same way, without clashing with the
+	// parameters
+	taken := map[string]bool{}
+	for _, p := range m.params {
+		taken[p.name] = true
+	}
+	for i, r := range method.Results {
+		name := r.Name
+		if name != "" && name != "_" && (reserved[name] || imports.Used(name) || taken[name]) {
+			name = fmt.Sprintf("result%d", i+1)
+			for taken[name] {
+				name += "_"
+			}
+		}
+		taken[name] = true
+		m.results = append(m.results, variable{name: name, typ: imports.TypeExpr(r.Type)})
+	}
+	return m
+}
+
+func (v variable) paramType() ast.Expr {
+	if v.variadic {
+		return &ast.Ellipsis{Elt: astcopy.Expr(v.typ)}
+	}
+	return astcopy.Expr(v.typ)
+}
+
+func (v variable) valueType() ast.Expr {
+	if v.variadic {
+		return &ast.ArrayType{Elt: astcopy.Expr(v.typ)}
+	}
+	return astcopy.Expr(v.typ)
+}
+
+// private returns the name of an unexported field of the method. The fields
+// of unexported methods are prefixed with mock, since their names would
+// otherwise be the same as those of the helper methods.
+func (m *mockMethod) private(suffix string) string {
+	r, size := utf8.DecodeRuneInString(m.name)
+	if unicode.IsLower(r) || r == '_' {
+		return "mock" + string(unicode.ToUpper(r)) + m.name[size:] + suffix
+	}
+	return string(unicode.ToLower(r)) + m.name[size:] + suffix
+}
+
+func (m *mockMethod) field(suffix string) ast.Expr {
+	return selector(ident("m"), m.private(suffix))
+}
+
+func (m *mockMethod) funcField() ast.Expr {
+	return selector(ident("m"), m.name+"Func")
+}
+
+func (m *mockMethod) funcType() *ast.FuncType {
+	params := make([]*ast.Field, len(m.params))
+	for i, p := range m.params {
+		params[i] = field(p.name, p.paramType())
+	}
+	var results []*ast.Field
+	for _, r := range m.results {
+		f := &ast.Field{Type: astcopy.Expr(r.typ)}
+		if r.name != "" {
+			f.Names = []*ast.Ident{ident(r.name)}
+		}
+		results = append(results, f)
+	}
+	return &ast.FuncType{
+		Params:  &ast.FieldList{List: params},
+		Results: fieldList(results),
+	}
+}
+
+func (m *mockMethod) argsType() *ast.StructType {
+	fields := make([]*ast.Field, len(m.params))
+	for i, p := range m.params {
+		fields[i] = field(fmt.Sprintf("arg%d", i+1), p.valueType())
+	}
+	return structType(fields)
+}
+
+func (m *mockMethod) returnsType() *ast.StructType {
+	fields := make([]*ast.Field, len(m.results))
+	for i, r := range m.results {
+		fields[i] = field(fmt.Sprintf("result%d", i+1), astcopy.Expr(r.typ))
+	}
+	return structType(fields)
+}
+
+// fields returns the struct fields needed by the mock of the method
+func (m *mockMethod) fields() []*ast.Field {
+	mockFunc := field(m.name+"Func", m.funcType())
+	mockFunc.Doc = docComment(fmt.Sprintf("%sFunc is called by %s when set", m.name, m.name), m.doc)
+	fields := []*ast.Field{
+		mockFunc,
+		field(m.private("Mutex"), selector(ident("sync"), "RWMutex")),
+		field(m.private("ArgsForCall"), &ast.ArrayType{Elt: m.argsType()}),
+	}
+	if len(m.results) > 0 {
+		fields = append(fields,
+			field(m.private("Returns"), m.returnsType()),
+			field(m.private("ReturnsOnCall"), &ast.MapType{Key: ident("int"), Value: m.returnsType()}),
+		)
+	}
+	return fields
+}
+
+// decls returns the mocked method together with its helper methods
+func (m *mockMethod) decls() []ast.Decl {
+	decls := []ast.Decl{m.mockDecl(), m.callCountDecl()}
+	if len(m.params) > 0 {
+		decls = append(decls, m.argsForCallDecl())
+	}
+	if len(m.results) > 0 {
+		decls = append(decls, m.returnsDecl(), m.returnsOnCallDecl())
+	}
+	return decls
+}
+
+func (m *mockMethod) funcDecl(name string, doc *ast.CommentGroup, typ *ast.FuncType, body []ast.Stmt) *ast.FuncDecl {
+	return &ast.FuncDecl{
+		Doc: doc,
+		Recv: &ast.FieldList{
+			List: []*ast.Field{
+				field("m", &ast.StarExpr{X: astcopy.Expr(m.recv)}),
+			},
+		},
+		Name: ident(name),
+		Type: typ,
+		Body: &ast.BlockStmt{
+			List: body,
+		},
+	}
+}
+
+func (m *mockMethod) lock(read bool) []ast.Stmt {
+	lock, unlock := "Lock", "Unlock"
+	if read {
+		lock, unlock = "RLock", "RUnlock"
+	}
+	return []ast.Stmt{
+		callStmt(selector(m.field("Mutex"), lock)),
+		&ast.DeferStmt{
+			Call: call(selector(m.field("Mutex"), unlock)),
+		},
+	}
+}
+
+func (m *mockMethod) mockDecl() ast.Decl {
+	args := make([]ast.Expr, len(m.params))
+	for i, p := range m.params {
+		args[i] = ident(p.name)
+	}
+	stubCall := call(ident("stub"), args...)
+	if len(m.params) > 0 && m.params[len(m.params)-1].variadic {
+		stubCall.Ellipsis = 1
+	}
+
+	body := []ast.Stmt{
+		callStmt(selector(m.field("Mutex"), "Lock")),
+	}
+	if len(m.results) > 0 {
+		body = append(body, assign(token.DEFINE,
+			[]ast.Expr{ident("ret"), ident("specificReturn")},
+			&ast.IndexExpr{
+				X:     m.field("ReturnsOnCall"),
+				Index: call(ident("len"), m.field("ArgsForCall")),
+			},
+		))
+	}
+	body = append(body,
+		assign(token.ASSIGN, []ast.Expr{m.field("ArgsForCall")}, call(ident("append"),
+			m.field("ArgsForCall"),
+			&ast.CompositeLit{Type: m.argsType(), Elts: args},
+		)),
+		assign(token.DEFINE, []ast.Expr{ident("stub")}, m.funcField()),
+	)
+	if len(m.results) > 0 {
+		body = append(body, assign(token.DEFINE, []ast.Expr{ident("returns")}, m.field("Returns")))
+	}
+	body = append(body, callStmt(selector(m.field("Mutex"), "Unlock")))
+
+	if len(m.results) == 0 {
+		body = append(body, ifStmt(
+			&ast.BinaryExpr{X: ident("stub"), Op: token.NEQ, Y: ident("nil")},
+			&ast.ExprStmt{X: stubCall},
+		))
+	} else {
+		body = append(body,
+			ifStmt(
+				&ast.BinaryExpr{X: ident("stub"), Op: token.NEQ, Y: ident("nil")},
+				returnStmt(stubCall),
+			),
+			ifStmt(ident("specificReturn"), returnStmt(m.resultFields("ret")...)),
+			returnStmt(m.resultFields("returns")...),
+		)
+	}
+
+	doc := docComment(fmt.Sprintf("%s mocks %s.%s", m.name, m.iface, m.name), m.doc)
+	return m.funcDecl(m.name, doc, m.funcType(), body)
+}
+
+func (m *mockMethod) resultFields(from string) []ast.Expr {
+	fields := make([]ast.Expr, len(m.results))
+	for i := range m.results {
+		fields[i] = selector(ident(from), fmt.Sprintf("result%d", i+1))
+	}
+	return fields
+}
+
+func (m *mockMethod) callCountDecl() ast.Decl {
+	body := append(m.lock(true), returnStmt(call(ident("len"), m.field("ArgsForCall"))))
+	return m.funcDecl(
+		m.name+"CallCount",
+		comment(fmt.Sprintf("// %sCallCount returns the number of times %s has been called", m.name, m.name)),
+		&ast.FuncType{
+			Params:  &ast.FieldList{},
+			Results: fieldList([]*ast.Field{{Type: ident("int")}}),
+		},
+		body,
+	)
+}
+
+func (m *mockMethod) argsForCallDecl() ast.Decl {
+	results := make([]*ast.Field, len(m.params))
+	values := make([]ast.Expr, len(m.params))
+	for i, p := range m.params {
+		results[i] = &ast.Field{Type: p.valueType()}
+		values[i] = selector(ident("args"), fmt.Sprintf("arg%d", i+1))
+	}
+
+	body := append(m.lock(true),
+		assign(token.DEFINE, []ast.Expr{ident("args")}, &ast.IndexExpr{
+			X:     m.field("ArgsForCall"),
+			Index: ident("i"),
+		}),
+		returnStmt(values...),
+	)
+	return m.funcDecl(
+		m.name+"ArgsForCall",
+		comment(fmt.Sprintf("// %sArgsForCall returns the arguments of the i:th (zero indexed) call to %s", m.name, m.name)),
+		&ast.FuncType{
+			Params:  &ast.FieldList{List: []*ast.Field{field("i", ident("int"))}},
+			Results: fieldList(results),
+		},
+		body,
+	)
+}
+
+func (m *mockMethod) resultParams() ([]*ast.Field, []ast.Expr) {
+	params := make([]*ast.Field, len(m.results))
+	values := make([]ast.Expr, len(m.results))
+	for i, r := range m.results {
+		name := fmt.Sprintf("result%d", i+1)
+		params[i] = field(name, astcopy.Expr(r.typ))
+		values[i] = ident(name)
+	}
+	return params, values
+}
+
+func (m *mockMethod) returnsDecl() ast.Decl {
+	params, values := m.resultParams()
+	body := append(m.lock(false),
+		assign(token.ASSIGN, []ast.Expr{m.funcField()}, ident("nil")),
+		assign(token.ASSIGN, []ast.Expr{m.field("Returns")}, &ast.CompositeLit{
+			Type: m.returnsType(),
+			Elts: values,
+		}),
+	)
+	return m.funcDecl(
+		m.name+"Returns",
+		comment(fmt.Sprintf("// %sReturns sets the values returned by all calls to %s, it replaces %sFunc", m.name, m.name, m.name)),
+		&ast.FuncType{Params: &ast.FieldList{List: params}},
+		body,
+	)
+}
+
+func (m *mockMethod) returnsOnCallDecl() ast.Decl {
+	params, values := m.resultParams()
+	params = append([]*ast.Field{field("i", ident("int"))}, params...)
+	body := append(m.lock(false),
+		assign(token.ASSIGN, []ast.Expr{m.funcField()}, ident("nil")),
+		ifStmt(
+			&ast.BinaryExpr{X: m.field("ReturnsOnCall"), Op: token.EQL, Y: ident("nil")},
+			assign(token.ASSIGN, []ast.Expr{m.field("ReturnsOnCall")}, &ast.CompositeLit{
+				Type: &ast.MapType{Key: ident("int"), Value: m.returnsType()},
+			}),
+		),
+		assign(token.ASSIGN, []ast.Expr{&ast.IndexExpr{
+			X:     m.field("ReturnsOnCall"),
+			Index: ident("i"),
+		}}, &ast.CompositeLit{
+			Type: m.returnsType(),
+			Elts: values,
+		}),
+	)
+	return m.funcDecl(
+		m.name+"ReturnsOnCall",
+		comment(fmt.Sprintf("// %sReturnsOnCall sets the values returned by the i:th (zero indexed) call to %s, it replaces %sFunc", m.name, m.name, m.name)),
+		&ast.FuncType{Params: &ast.FieldList{List: params}},
+		body,
+	)
+}
@@ -0,0 +1,43 @@
+package mockgen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateNamedResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+	}{
+		{name: "reserved names", method: "Get(key string) (ret int, err error)"},
+		{name: "stub", method: "Get(key string) (stub string)"},
+		{name: "returns and specificReturn", method: "Get() (returns, specificReturn bool)"},
+		{name: "receiver", method: "Get() (m map[string]int)"},
+		{name: "builtins", method: "Get(len int) (append []int)"},
+		{name: "clash with renamed parameter", method: "Get(_ int, ret string) (var1 int, result2 error)"},
+		{name: "imported package", method: "Get() (context context.Context)"},
+		{name: "blank", method: "Get() (_ int, _ error)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "store.go")
+			src := "package store\n\nimport \"context\"\n\nvar _ context.Context\n\ntype Store interface {\n\t" + test.method + "\n}\n"
+			if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			g := New(WithVerify(), WithLayout(Layout{Package: TestPackage}))
+			files, err := g.GenerateFiles(context.Background(), Request{Path: path, Name: "Store"})
+			if err != nil {
+				t.Fatalf("the mock of %s does not type check: %v", test.method, err)
+			}
+			if len(files) != 1 || len(files[0].Source) == 0 {
+				t.Fatalf("expected one generated file, got %d", len(files))
+			}
+		})
+	}
+}
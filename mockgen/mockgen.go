@@ -1,56 +1,118 @@
 package mockgen
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"go/ast"
-	"go/format"
+	"go/importer"
+	"go/parser"
 	"go/token"
 	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
 
-	"github.com/lindell/mockay/astcopy"
+	"github.com/lindell/mockay/mockgen/model"
 )
 
+// mockName is the name of the generated mock struct, unless another name is
+// requested
+const mockName = "Mocked"
+
 // Generator does contain information what should be fixed in the code and how
 type Generator struct {
 	logger   Logger
-	fset     token.FileSet
 	position *Position
+	name     string
 	writer   io.Writer
+	inPlace  string
+	output   string
+	overlay  map[string][]byte
+	template *template.Template
+	style    string
+	layout   Layout
+	workers  int
+	cacheDir string
+	// pending are the cache entries of the packages generated since the
+	// last call to WriteFiles
+	pending *pendingEntries
+	// constraint is the build constraint of mocks, if it is set
+	constraint *string
+	verify     bool
+	loader     *loader
+	// std imports the standard library when verifying mocks
+	std *stdImporter
 }
 
 // New creates a new Generator
 func New(opts ...Option) *Generator {
 	f := &Generator{
-		logger: &nopLogger{},
-		writer: os.Stdout,
+		logger:  &nopLogger{},
+		writer:  os.Stdout,
+		workers: runtime.GOMAXPROCS(0),
+		pending: &pendingEntries{},
 	}
 	for _, opt := range opts {
 		opt(f)
 	}
+	f.loader = newLoader(f.overlay, f.logger)
+	if f.verify {
+		f.std = &stdImporter{importer: importer.ForCompiler(f.loader.fset, "source", nil)}
+	}
 	return f
 }
 
 // Option is an option for the mock generator
 type Option func(*Generator)
 
-// Logger is the logger used
+// Logger is the logger used. The arguments after the message are alternating
+// keys and values, as for log/slog which *slog.Logger implements it with.
 type Logger interface {
-	Info(string)
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
 }
 
+var _ Logger = (*slog.Logger)(nil)
+
 // nopLogger is used when no other logger is specified
 type nopLogger struct{}
 
-func (n *nopLogger) Info(string) {}
+func (n *nopLogger) Debug(string, ...interface{}) {}
+func (n *nopLogger) Info(string, ...interface{})  {}
+func (n *nopLogger) Warn(string, ...interface{})  {}
+
+// NewSlogLogger creates a Logger writing to a slog handler, e.g. a
+// slog.NewTextHandler writing to stderr
+func NewSlogLogger(handler slog.Handler) Logger {
+	return slog.New(handler)
+}
 
-// Position is the position in a document
+// Position is the position in a document, either as a line (X) and column (Y),
+// both starting at 1, or as a byte offset when X is 0
 type Position struct {
-	X int
-	Y int
+	X      int
+	Y      int
+	Offset int
+	// Encoding is how the column is counted, in bytes if not set
+	Encoding Encoding
 }
 
-// WithLogger sets a logger of the
+// Encoding is the encoding a column is counted in
+type Encoding int
+
+const (
+	// UTF8 columns are counted in bytes
+	UTF8 Encoding = iota
+	// UTF16 columns are counted in UTF-16 code units, as used by LSP
+	UTF16
+)
+
+// WithLogger sets the logger of the generator
 func WithLogger(logger Logger) Option {
 	return func(f *Generator) { f.logger = logger }
 }
@@ -60,141 +122,485 @@ func WithPosition(pos Position) Option {
 	return func(f *Generator) { f.position = &pos }
 }
 
+// WithName sets the name of the interface to mock, declared in the package of
+// the file given to Generate. It is used instead of the position.
+func WithName(name string) Option {
+	return func(f *Generator) { f.name = name }
+}
+
 // WithWriter sets the priter to be used
 func WithWriter(writer io.Writer) Option {
 	return func(f *Generator) { f.writer = writer }
 }
 
+// WithInPlace makes the mock be written into the file at path instead of the
+// writer. A mock previously generated in that file is replaced while all other
+// declarations are kept.
+func WithInPlace(path string) Option {
+	return func(f *Generator) { f.inPlace = path }
+}
+
+// WithOutput sets the path the mock written to the writer is meant for, the
+// package and imports of the mock are those of a file at that path. The path
+// given by the layout of the generator is used if not set.
+func WithOutput(path string) Option {
+	return func(f *Generator) { f.output = path }
+}
+
+// WithOverlay sets the contents to use for files instead of reading them from
+// disk, e.g. buffers modified in an editor or a file read from stdin.
+func WithOverlay(files map[string][]byte) Option {
+	return func(f *Generator) {
+		if f.overlay == nil {
+			f.overlay = map[string][]byte{}
+		}
+		for path, src := range files {
+			f.overlay[overlayKey(path)] = src
+		}
+	}
+}
+
+// SetOverlay sets the content to use for the file at path, as WithOverlay
+// does, or makes it be read from disk again if src is nil. The file and its
+// package are parsed again when next used, other files are kept. It must not
+// be called while mocks are generated.
+func (f *Generator) SetOverlay(path string, src []byte) {
+	if f.overlay == nil {
+		f.overlay = map[string][]byte{}
+		f.loader.overlay = f.overlay
+	}
+	if src == nil {
+		delete(f.overlay, overlayKey(path))
+	} else {
+		f.overlay[overlayKey(path)] = src
+	}
+	f.loader.forget(path)
+}
+
+// WithTemplate makes mocks be rendered from a template, executed with the
+// *model.Interface to mock, instead of the built in style. See TemplateFuncs
+// for the functions available in the template.
+func WithTemplate(tmpl *template.Template) Option {
+	return func(f *Generator) { f.template = tmpl }
+}
+
+// WithStyle selects the emitter, registered with RegisterEmitter, that
+// creates the mocks. DefaultStyle is used if not set.
+func WithStyle(name string) Option {
+	return func(f *Generator) { f.style = name }
+}
+
+// WithWorkers sets how many mocks GenerateAll generates at once, it is the
+// number of CPUs if not set
+func WithWorkers(n int) Option {
+	return func(f *Generator) {
+		if n > 0 {
+			f.workers = n
+		}
+	}
+}
+
+// Request describes a mock to generate
+type Request struct {
+	// Path is the file containing the interface, or a usage of it
+	Path string
+	// Position of the interface, or of a usage of it, in Path. The position
+	// of the generator is used if not set.
+	Position *Position
+	// Name of the interface, declared in the package of Path. It is used
+	// instead of the position when set. The name of the generator is used
+	// if neither a position nor a name is set.
+	Name string
+	// Output is the path the mock is meant to be written to. If not set, the
+	// path is given by the layout of the generator for the directory of Path,
+	// by default the one returned by MockPath.
+	Output string
+	// Merge makes the source of the generated file be the current content of
+	// Output, with a mock previously generated in it replaced
+	Merge bool
+	// MockName is the name of the mock type, Mocked if not set. It is not
+	// used when rendering a template.
+	MockName string
+	// Style is the style of the mock, used instead of the style or the
+	// template of the generator when set
+	Style string
+	// SkipUnchanged makes the file be returned as unchanged, without being
+	// generated, if the hash of the mock is the one in the header of Output
+	SkipUnchanged bool
+}
+
+// GeneratedFile is a generated mock file
+type GeneratedFile struct {
+	// Path is where the file is meant to be written
+	Path string
+	// File is the syntax tree of the generated mock
+	File *ast.File
+	// Source is the formatted source of the file
+	Source []byte
+	// Interfaces are the names of the mocked interfaces
+	Interfaces []string
+	// Hash identifies the method sets of the interfaces and the options
+	// the file was generated with, it is written in the header of the file
+	Hash string
+	// Unchanged is set if the file at Path was already generated with the
+	// same hash, File and Source are then not set
+	Unchanged bool
+}
+
 // Generate a mock
 func (f *Generator) Generate(path string) error {
-	typeSpec, err := f.findInterfaceTypeSpec(path)
+	output := f.output
+	if f.inPlace != "" {
+		output = f.inPlace
+	}
+	files, err := f.GenerateFiles(context.Background(), Request{
+		Path:   path,
+		Output: output,
+		Merge:  f.inPlace != "",
+	})
 	if err != nil {
 		return err
 	}
 
-	var fieldList []*ast.Field
-	var funcDecs []ast.Decl
-	interf := typeSpec.Type.(*ast.InterfaceType)
-	for _, method := range interf.Methods.List {
-		name := method.Names[0].Name
-		fun := method.Type.(*ast.FuncType)
-
-		mockFunc := &ast.Field{
-			Names: []*ast.Ident{
-				{
-					Name: name + "Func",
-				},
-			},
-			Type: astcopy.FuncType(fun),
-		}
-		fieldList = append(fieldList, mockFunc)
-
-		params := copyFieldList(fun.Params)
-
-		funcDec := &ast.FuncDecl{
-			Doc: comment("// " + name + " mock"),
-			Recv: &ast.FieldList{
-				List: []*ast.Field{
-					{
-						Names: []*ast.Ident{
-							{
-								Name: "m",
-							},
-						},
-						Type: &ast.StarExpr{
-							X: &ast.Ident{
-								Name: "Mocked",
-							},
-						},
-					},
-				},
-			},
-			Name: &ast.Ident{
-				Name: name,
-			},
-			Type: &ast.FuncType{
-				Params:  params,
-				Results: astcopy.FieldList(fun.Results),
-			},
-			Body: &ast.BlockStmt{
-				List: []ast.Stmt{
-					&ast.ReturnStmt{
-						Results: []ast.Expr{
-							&ast.CallExpr{
-								Fun: &ast.SelectorExpr{
-									X: &ast.Ident{
-										Name: "m",
-									},
-									Sel: &ast.Ident{
-										Name: name + "Func",
-									},
-								},
-								Args: argsFromParams(params),
-							},
-						},
-					},
-				},
-			},
-		}
-		funcDecs = append(funcDecs, funcDec)
-	}
-
-	genStruct := &ast.GenDecl{
-		Doc: &ast.CommentGroup{
-			List: []*ast.Comment{
-				{
-					Text: "// Mocked ...",
-				},
-			},
-		},
-		Tok: token.TYPE,
-		Specs: []ast.Spec{
-			&ast.TypeSpec{
-				Name: &ast.Ident{
-					Name: "Mocked",
-				},
-				Type: &ast.StructType{
-					Fields: &ast.FieldList{
-						List: fieldList,
-					},
-				},
-			},
-		},
+	for _, file := range files {
+		if f.inPlace != "" {
+			f.logger.Info("writing mock", "path", file.Path)
+			err = os.WriteFile(file.Path, file.Source, 0660)
+		} else {
+			_, err = f.writer.Write(file.Source)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateFiles generates mocks and returns them instead of writing them
+func (f *Generator) GenerateFiles(ctx context.Context, req Request) ([]GeneratedFile, error) {
+	p, err := f.plan(req)
+	if err != nil {
+		return nil, err
+	}
+	file, err := f.generateMocks(ctx, []*mockPlan{p}, req.Merge, req.SkipUnchanged)
+	if err != nil {
+		return nil, err
+	}
+	return []GeneratedFile{file}, nil
+}
+
+// generateMocks generates the file of the planned mocks, which all have the
+// same output. Only a single mock may be merged into the file at the output.
+func (f *Generator) generateMocks(ctx context.Context, plans []*mockPlan, merge, skipUnchanged bool) (GeneratedFile, error) {
+	if err := ctx.Err(); err != nil {
+		return GeneratedFile{}, err
+	}
+	hash, err := sharedHash(plans)
+	if err != nil {
+		return GeneratedFile{}, err
+	}
+	first, output := plans[0], plans[0].output
+	generated := GeneratedFile{Path: output, Interfaces: interfaceNames(plans), Hash: hash}
+	if skipUnchanged && !merge && f.unchanged(output, hash) {
+		f.logger.Debug("mock is unchanged", "path", output, "hash", hash)
+		generated.Unchanged = true
+		return generated, nil
+	}
+
+	var file *ast.File
+	var src []byte
+	merged := false
+	switch {
+	case len(plans) > 1 && merge:
+		return GeneratedFile{}, fmt.Errorf("can not merge several mocks into %s", output)
+	case len(plans) > 1:
+		file, src, err = f.renderShared(plans)
+	case first.template != nil && merge:
+		return GeneratedFile{}, errTemplateMerge
+	case first.template != nil:
+		file, src, err = renderTemplate(first.template, first.iface)
+	default:
+		file, err = f.emit(first.iface, first.pkg, first.mock, first.style)
+		if err == nil && merge {
+			src, merged, err = f.mergeFile(output, file, first.mock)
+		} else if err == nil {
+			src, err = printFile(file)
+		}
+	}
+	if err != nil {
+		return GeneratedFile{}, err
+	}
+	// A file the mock is merged into keeps its own header and constraint,
+	// files that did not exist are generated like any other
+	if merged {
+		src = replaceHash(src, hash)
+	} else {
+		mocks := make([]string, len(plans))
+		for i, p := range plans {
+			mocks[i] = p.mockHeader()
+		}
+		src = withHeader(withConstraint(src, first.constraint), hash, mocks...)
+	}
+	if f.verify {
+		for _, p := range plans {
+			if err := f.verifyMock(p, src); err != nil {
+				return GeneratedFile{}, err
+			}
+		}
+	}
+
+	generated.File, generated.Source = file, src
+	return generated, nil
+}
+
+// renderShared renders the planned mocks, of the same package, into one file
+// with the imports of all of them
+func (f *Generator) renderShared(plans []*mockPlan) (*ast.File, []byte, error) {
+	srcs := make([][]byte, len(plans))
+	for i, p := range plans {
+		var err error
+		if p.template != nil {
+			_, srcs[i], err = renderTemplate(p.template, p.iface)
+		} else {
+			var file *ast.File
+			file, err = f.emit(p.iface, p.pkg, p.mock, p.style)
+			if err == nil {
+				srcs[i], err = printFile(file)
+			}
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	src, err := joinSources(plans[0].pkg.Name, srcs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not write the mocks of %s to %s: %w", strings.Join(interfaceNames(plans), ", "), plans[0].output, err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, src, nil
+}
+
+// mockPlan is a request with the interface parsed and the defaults of the
+// generator applied
+type mockPlan struct {
+	iface    *model.Interface
+	output   string
+	mock     string
+	style    string
+	template *template.Template
+	// pkg is the package the mock is generated in
+	pkg model.Package
+	// constraint is the expression of the //go:build line of the mock
+	constraint string
+	hash       string
+}
+
+// mockHeader returns the mock as named in the header of the generated file,
+// the name of the interface followed by the name of the mock type. Templates
+// name the type themselves.
+func (p *mockPlan) mockHeader() string {
+	if p.template != nil {
+		return p.iface.Name
+	}
+	return p.iface.Name + " " + p.mock
+}
+
+// inPackage returns if the mock is generated in the package of the interface
+func (p *mockPlan) inPackage() bool {
+	return p.pkg.Dir == p.iface.Package.Dir && p.pkg.Name == p.iface.Package.Name
+}
+
+// plan parses the interface of the request and decides how to generate it
+func (f *Generator) plan(req Request) (*mockPlan, error) {
+	position, name := f.target(req)
+	iface, err := f.parseInterface(req.Path, position, name)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &mockPlan{
+		iface:    iface,
+		output:   req.Output,
+		mock:     req.MockName,
+		style:    f.style,
+		template: f.template,
+	}
+	if p.output == "" {
+		p.output, err = f.outputPath(iface, filepath.Dir(overlayKey(req.Path)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.pkg = f.outputPackage(p.output)
+	if p.mock == "" {
+		p.mock = mockName
+	}
+	if p.mock == iface.Name && p.inPackage() {
+		// A mock named after the interface would clash with it
+		p.mock = "Mock" + iface.Name
+	}
+	if req.Style != "" {
+		p.style, p.template = req.Style, nil
+	}
+	// Templates render the package clause themselves
+	if p.template == nil && !p.inPackage() {
+		if err := f.checkExported(iface); err != nil {
+			return nil, err
+		}
+	}
+	p.constraint = iface.BuildConstraint
+	if f.constraint != nil {
+		p.constraint = *f.constraint
+	}
+	p.hash = f.mockHash(p)
+	return p, nil
+}
+
+// Describe returns the model of the interface of the request. All interfaces
+// declared in the file are returned if neither a position nor a name is set.
+func (f *Generator) Describe(ctx context.Context, req Request) ([]*model.Interface, error) {
+	position, name := f.target(req)
+	if position != nil || name != "" {
+		iface, err := f.parseInterface(req.Path, position, name)
+		if err != nil {
+			return nil, err
+		}
+		return []*model.Interface{iface}, nil
+	}
+
+	file, err := f.loader.openFile(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	var ifaces []*model.Interface
+	for _, spec := range file.interfaces() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		iface, err := f.loader.parseInterface(file, spec)
+		if err != nil {
+			f.logger.Warn("skipping interface", "name", spec.Name.Name, "err", err)
+			continue
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces, nil
+}
+
+// target returns the position and name of the request, or those of the
+// generator if neither is set in the request
+func (f *Generator) target(req Request) (*Position, string) {
+	if req.Position != nil || req.Name != "" {
+		return req.Position, req.Name
+	}
+	return f.position, f.name
+}
+
+// parseInterface finds the interface with the name, or at the position, and
+// creates its model
+func (f *Generator) parseInterface(path string, position *Position, name string) (*model.Interface, error) {
+	var file *file
+	var typeSpec *ast.TypeSpec
+	var err error
+	if name != "" {
+		file, typeSpec, err = f.findNamedTypeSpec(path, name)
+	} else {
+		file, typeSpec, err = f.findInterfaceTypeSpec(path, position)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f.loader.parseInterface(file, typeSpec)
+}
+
+// emit creates the mock called name of an interface in the package pkg, with
+// the emitter of the style
+func (f *Generator) emit(iface *model.Interface, pkg model.Package, name, style string) (*ast.File, error) {
+	emitter, err := lookupEmitter(style)
+	if err != nil {
+		return nil, err
+	}
+	return generateFile(iface, name, pkg, emitter)
+}
+
+// generateFile creates the mock called name of an interface with an emitter,
+// in a file that is part of the package pkg
+func generateFile(iface *model.Interface, name string, pkg model.Package, emitter Emitter) (*ast.File, error) {
+	var local *model.Import
+	if iface.Package.Path == pkg.Path {
+		local = &model.Import{Name: iface.Package.Name, Path: iface.Package.Path}
+	}
+	imports := newImports(local)
+	for _, imp := range iface.Imports() {
+		if !imports.IsLocal(imp) {
+			imports.Add(imp)
+		}
+	}
+
+	decls, required, err := emitter.Emit(&Mock{Name: name, Interface: iface, Imports: imports})
+	if err != nil {
+		return nil, err
+	}
+	for _, imp := range required {
+		if name := imports.Add(imp); name != imp.Name {
+			return nil, fmt.Errorf("the import of %s conflicts with another package named %s", imp.Path, imp.Name)
+		}
 	}
 
-	decls := []ast.Decl{genStruct}
-	decls = append(decls, funcDecs...)
 	file := &ast.File{
 		Name: &ast.Ident{
-			Name: "mock",
+			Name: pkg.Name,
 		},
-		Decls: decls,
+		Decls: append([]ast.Decl{imports.decl()}, decls...),
 	}
 
-	// fmt.Println(typeSpec)
-	// ast.Print(nil, genStruct)
+	return file, nil
+}
 
-	fset := token.NewFileSet()
-	err = format.Node(f.writer, fset, file)
+// findNamedTypeSpec finds the interface declared with the name in the package
+// of the file at path
+func (f *Generator) findNamedTypeSpec(path, name string) (*file, *ast.TypeSpec, error) {
+	file, err := f.loader.openFile(path)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-
-	return nil
+	// The file may be left out of its package by build constraints
+	if spec := file.lookupType(name); spec != nil {
+		if _, ok := spec.Type.(*ast.InterfaceType); ok {
+			return file, spec, nil
+		}
+	}
+	declFile, inter, err := f.loader.resolveExpr(file, ident(name), 0)
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) && notFound.Name == name {
+		notFound.Candidates = f.loader.packageCandidates(file)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return declFile, inter, nil
 }
 
-func (f *Generator) findInterfaceTypeSpec(path string) (*ast.TypeSpec, error) {
-	file, err := openFile(path)
+func (f *Generator) findInterfaceTypeSpec(path string, position *Position) (*file, *ast.TypeSpec, error) {
+	file, err := f.loader.openFile(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if f.position == nil {
-		return nil, errors.New("did not get any position")
+	if position == nil {
+		return nil, nil, ErrNoTarget
 	}
 
 	// ast.Print(file.fset, file.astFile)
 
+	pos, err := file.tokenPos(*position)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	node := file.findAtPosition(func(n ast.Node) bool {
 		spec, ok := n.(*ast.TypeSpec)
 		if !ok {
@@ -203,10 +609,27 @@ func (f *Generator) findInterfaceTypeSpec(path string) (*ast.TypeSpec, error) {
 
 		_, ok = spec.Type.(*ast.InterfaceType)
 		return ok
-	}, f.position.X, f.position.Y)
-	if node == nil {
-		return nil, errors.New("could not find interface")
+	}, pos)
+
+	var inter *ast.TypeSpec
+	if node != nil {
+		inter = node.(*ast.TypeSpec)
+	} else if inter = file.inlineInterfaceAt(pos); inter != nil {
+		f.logger.Info("using anonymous interface", "name", interfaceName(inter))
+		return file, inter, nil
+	} else {
+		// The position might be at a usage of an interface
+		declFile, resolved, err := f.loader.resolveAt(file, pos)
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) && notFound.Position.Line == 0 {
+			notFound.Position = file.position(pos)
+			notFound.Candidates = file.candidates(notFound.Position.Line)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		file, inter = declFile, resolved
+		f.logger.Info("resolved interface", "name", interfaceName(inter), "file", file.path)
 	}
-	inter := node.(*ast.TypeSpec)
-	return inter, nil
+	return file, inter, nil
 }
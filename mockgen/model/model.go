@@ -0,0 +1,160 @@
+// Package model describes interfaces to be mocked, independently of how they
+// were parsed and how the mocks are emitted
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Interface is an interface to be mocked
+type Interface struct {
+	Name string `json:"name"`
+	// Doc is the text of the doc comment, without comment markers
+	Doc        string   `json:"doc,omitempty"`
+	Package    Package  `json:"package"`
+	TypeParams []Param  `json:"typeParams,omitempty"`
+	Methods    []Method `json:"methods"`
+	Position   Position `json:"position"`
+	// BuildConstraint is the build constraint of the file declaring the
+	// interface, as the expression of a //go:build line, including the
+	// constraint implied by a _GOOS or _GOARCH suffix of the file name
+	BuildConstraint string `json:"buildConstraint,omitempty"`
+}
+
+// Package is the package an interface is declared in
+type Package struct {
+	Name string `json:"name"`
+	// Path is the import path, empty if it could not be determined
+	Path string `json:"path,omitempty"`
+	Dir  string `json:"dir,omitempty"`
+}
+
+// Method is a method of an interface, including methods of embedded interfaces
+type Method struct {
+	Name string `json:"name"`
+	// Doc is the text of the doc comment, without comment markers
+	Doc     string   `json:"doc,omitempty"`
+	Params  []Param  `json:"params"`
+	Results []Result `json:"results"`
+	// Variadic is set if the last parameter is variadic, its type is then
+	// the type of the elements
+	Variadic bool     `json:"variadic"`
+	Position Position `json:"position"`
+}
+
+// Param is a parameter of a method, or a type parameter of an interface where
+// the type is the constraint
+type Param struct {
+	// Name is the name in the source, it may be empty or _
+	Name string  `json:"name"`
+	Type TypeRef `json:"type"`
+}
+
+// Result is a result of a method
+type Result struct {
+	// Name is the name in the source, empty for unnamed results
+	Name string  `json:"name"`
+	Type TypeRef `json:"type"`
+}
+
+// TypeRef is a reference to a type
+type TypeRef struct {
+	// Expr is the type as Go source. Types declared in packages, including
+	// the package of the interface, are qualified with the package name,
+	// e.g. map[string]*http.Request.
+	Expr string `json:"expr"`
+	// Imports are the packages referred to by Expr
+	Imports []Import `json:"imports,omitempty"`
+}
+
+// Import is a package referred to by a type
+type Import struct {
+	// Name is the name the package is referred to with in TypeRef.Expr
+	Name string `json:"name"`
+	// Path is the import path, empty if it could not be determined
+	Path string `json:"path"`
+}
+
+// Position is a position in a source file
+type Position struct {
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Offset   int    `json:"offset"`
+}
+
+// Imports returns all packages referred to by the types of the interface,
+// sorted by path and without duplicates
+func (i *Interface) Imports() []Import {
+	seen := map[Import]bool{}
+	var imports []Import
+	add := func(ref TypeRef) {
+		for _, imp := range ref.Imports {
+			if !seen[imp] {
+				seen[imp] = true
+				imports = append(imports, imp)
+			}
+		}
+	}
+	for _, p := range i.TypeParams {
+		add(p.Type)
+	}
+	for _, m := range i.Methods {
+		for _, p := range m.Params {
+			add(p.Type)
+		}
+		for _, r := range m.Results {
+			add(r.Type)
+		}
+	}
+	sort.Slice(imports, func(a, b int) bool {
+		if imports[a].Path != imports[b].Path {
+			return imports[a].Path < imports[b].Path
+		}
+		return imports[a].Name < imports[b].Name
+	})
+	return imports
+}
+
+// MethodSet returns the method set of the interface as text, one method per
+// line sorted by name, e.g. Get(ctx context.Context, key string) (*Row,
+// error). The names of parameters and results are included, as mocks use
+// them, as are type parameters and the import paths of the packages used.
+// Docs and positions are left out, mocks with the same method set differ in
+// the docs they repeat.
+func (i *Interface) MethodSet() string {
+	var lines []string
+	if len(i.TypeParams) > 0 {
+		params := make([]string, len(i.TypeParams))
+		for n, p := range i.TypeParams {
+			params[n] = p.Name + " " + p.Type.Expr
+		}
+		lines = append(lines, "["+strings.Join(params, ", ")+"]")
+	}
+
+	methods := make([]string, len(i.Methods))
+	for n, m := range i.Methods {
+		params := make([]string, len(m.Params))
+		for k, p := range m.Params {
+			params[k] = p.Type.Expr
+			if m.Variadic && k == len(m.Params)-1 {
+				params[k] = "..." + params[k]
+			}
+			params[k] = strings.TrimSpace(p.Name + " " + params[k])
+		}
+		results := make([]string, len(m.Results))
+		for k, r := range m.Results {
+			results[k] = strings.TrimSpace(r.Name + " " + r.Type.Expr)
+		}
+		methods[n] = m.Name + "(" + strings.Join(params, ", ") + ") (" + strings.Join(results, ", ") + ")"
+	}
+	sort.Strings(methods)
+	lines = append(lines, methods...)
+
+	for _, imp := range i.Imports() {
+		lines = append(lines, "import "+imp.Name+" "+strconv.Quote(imp.Path))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,32 @@
+package model
+
+import "testing"
+
+func TestMethodSet(t *testing.T) {
+	iface := func(param, result string) *Interface {
+		return &Interface{
+			Name: "Store",
+			Methods: []Method{{
+				Name:    "Get",
+				Params:  []Param{{Name: param, Type: TypeRef{Expr: "string"}}},
+				Results: []Result{{Name: result, Type: TypeRef{Expr: "int"}}},
+			}},
+		}
+	}
+
+	base := iface("key", "ret")
+	if got, want := base.MethodSet(), "Get(key string) (ret int)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	documented := iface("key", "ret")
+	documented.Methods[0].Doc = "Get gets"
+	documented.Methods[0].Position = Position{Filename: "store.go", Line: 3}
+	if documented.MethodSet() != base.MethodSet() {
+		t.Error("docs and positions changed the method set")
+	}
+	for _, renamed := range []*Interface{iface("k", "ret"), iface("key", "n"), iface("key", "")} {
+		if renamed.MethodSet() == base.MethodSet() {
+			t.Errorf("renaming changed nothing in %q", renamed.MethodSet())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package mockgen
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// MockPath returns the default path of the mock of an interface declared in
+// a file in dir, a file named after the interface in the mock package next to it
+func MockPath(dir, interfaceName string) string {
+	return filepath.Join(dir, "mock", snakeCase(interfaceName)+".go")
+}
+
+// snakeCase converts a name like HTTPClient to http_client
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// camelCase converts a name like HTTPClient or http_client to httpClient
+func camelCase(name string) string {
+	words := strings.Split(snakeCase(name), "_")
+	var b strings.Builder
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			w = exported(w)
+		}
+		b.WriteString(w)
+	}
+	return b.String()
+}
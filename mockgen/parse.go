@@ -0,0 +1,437 @@
+package mockgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lindell/mockay/astcopy"
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// typeContext is what is needed to turn type expressions in a file into
+// model.TypeRef, where types declared in packages are qualified
+type typeContext struct {
+	loader *loader
+	file   *file
+	pkg    model.Package
+	// typeParams are the names of the type parameters of the interface,
+	// they are never qualified
+	typeParams map[string]bool
+	// args are the type arguments of an embedded generic interface, by the
+	// name of the type parameter they replace
+	args map[string]model.TypeRef
+}
+
+// parseInterface creates the model of an interface declared in f
+func (l *loader) parseInterface(f *file, spec *ast.TypeSpec) (*model.Interface, error) {
+	ctx := l.typeContext(f)
+
+	pos := spec.Name.Pos()
+	if !pos.IsValid() {
+		// Anonymous interfaces are given a name without a position
+		pos = spec.Type.Pos()
+	}
+	iface := &model.Interface{
+		Name:            spec.Name.Name,
+		Doc:             docText(f.typeSpecDoc(spec)),
+		Package:         ctx.pkg,
+		Position:        f.position(pos),
+		BuildConstraint: f.buildConstraint(),
+	}
+	if spec.TypeParams != nil {
+		for _, field := range spec.TypeParams.List {
+			for _, name := range field.Names {
+				ctx.typeParams[name.Name] = true
+			}
+		}
+		for _, field := range spec.TypeParams.List {
+			constraint := ctx.typeRef(field.Type)
+			for _, name := range field.Names {
+				iface.TypeParams = append(iface.TypeParams, model.Param{Name: name.Name, Type: constraint})
+			}
+		}
+	}
+
+	methods, err := ctx.methods(spec.Type.(*ast.InterfaceType), map[string]bool{}, 0)
+	var unsupported *UnsupportedTypeError
+	if errors.As(err, &unsupported) && unsupported.Name == "" {
+		unsupported.Name = spec.Name.Name
+	}
+	if err != nil {
+		return nil, err
+	}
+	iface.Methods = methods
+	return iface, nil
+}
+
+func (l *loader) typeContext(f *file) *typeContext {
+	dir := filepath.Dir(overlayKey(f.path))
+	pkg := model.Package{
+		Name: f.astFile.Name.Name,
+		Path: l.importPath(dir),
+		Dir:  dir,
+	}
+	if pkg.Path != "" && strings.HasSuffix(pkg.Name, "_test") && strings.HasSuffix(f.path, "_test.go") {
+		// An external test package
+		pkg.Path += "_test"
+	}
+	return &typeContext{
+		loader:     l,
+		file:       f,
+		pkg:        pkg,
+		typeParams: map[string]bool{},
+	}
+}
+
+// methods returns the methods of an interface type, including the methods of
+// embedded interfaces. Methods already in seen are skipped.
+func (c *typeContext) methods(iface *ast.InterfaceType, seen map[string]bool, depth int) ([]model.Method, error) {
+	if depth > maxResolveDepth {
+		return nil, fmt.Errorf("too many embedded interfaces to follow")
+	}
+
+	var methods []model.Method
+	for _, field := range iface.Methods.List {
+		if len(field.Names) > 0 {
+			name := field.Names[0].Name
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			methods = append(methods, c.method(name, field))
+			continue
+		}
+
+		embedded, err := c.embedded(field.Type, seen, depth)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, embedded...)
+	}
+	return methods, nil
+}
+
+// embedded returns the methods of an interface embedded in another
+func (c *typeContext) embedded(expr ast.Expr, seen map[string]bool, depth int) ([]model.Method, error) {
+	if id, ok := expr.(*ast.Ident); ok && id.Obj == nil && types.Universe.Lookup(id.Name) != nil {
+		switch id.Name {
+		case "error":
+			if seen["Error"] {
+				return nil, nil
+			}
+			seen["Error"] = true
+			return []model.Method{{
+				Name:    "Error",
+				Params:  []model.Param{},
+				Results: []model.Result{{Type: model.TypeRef{Expr: "string"}}},
+			}}, nil
+		case "any", "comparable":
+			return nil, nil
+		}
+	}
+
+	var args []ast.Expr
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		args = []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		args = e.Indices
+	case *ast.BinaryExpr, *ast.UnaryExpr:
+		return nil, &UnsupportedTypeError{
+			Position: c.file.position(expr.Pos()),
+			Reason:   "interfaces with type sets can only be used as constraints",
+		}
+	}
+
+	declFile, spec, err := c.loader.resolveExpr(c.file, expr, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	inner := c.loader.typeContext(declFile)
+	inner.args = map[string]model.TypeRef{}
+	if spec.TypeParams != nil {
+		i := 0
+		for _, field := range spec.TypeParams.List {
+			for _, name := range field.Names {
+				if i < len(args) {
+					inner.args[name.Name] = c.typeRef(args[i])
+				}
+				i++
+			}
+		}
+	}
+	return inner.methods(spec.Type.(*ast.InterfaceType), seen, depth+1)
+}
+
+func (c *typeContext) method(name string, field *ast.Field) model.Method {
+	fun := field.Type.(*ast.FuncType)
+	method := model.Method{
+		Name:     name,
+		Doc:      docText(field.Doc),
+		Params:   []model.Param{},
+		Results:  []model.Result{},
+		Position: c.file.position(field.Pos()),
+	}
+	for _, f := range fieldsOf(fun.Params) {
+		typ := f.Type
+		if ellipsis, ok := typ.(*ast.Ellipsis); ok {
+			typ = ellipsis.Elt
+			method.Variadic = true
+		}
+		ref := c.typeRef(typ)
+		for _, name := range namesOf(f) {
+			method.Params = append(method.Params, model.Param{Name: name, Type: ref})
+		}
+	}
+	for _, f := range fieldsOf(fun.Results) {
+		ref := c.typeRef(f.Type)
+		for _, name := range namesOf(f) {
+			method.Results = append(method.Results, model.Result{Name: name, Type: ref})
+		}
+	}
+	return method
+}
+
+func fieldsOf(list *ast.FieldList) []*ast.Field {
+	if list == nil {
+		return nil
+	}
+	return list.List
+}
+
+// namesOf returns the names of a field, or a single empty name if it has none
+func namesOf(f *ast.Field) []string {
+	if len(f.Names) == 0 {
+		return []string{""}
+	}
+	names := make([]string, len(f.Names))
+	for i, n := range f.Names {
+		names[i] = n.Name
+	}
+	return names
+}
+
+// typeRef creates a reference to the type expression expr
+func (c *typeContext) typeRef(expr ast.Expr) model.TypeRef {
+	var imports []model.Import
+	add := func(imp model.Import) {
+		for _, i := range imports {
+			if i == imp {
+				return
+			}
+		}
+		imports = append(imports, imp)
+	}
+
+	qualified := mapTypeExpr(expr, func(e ast.Expr) ast.Expr {
+		switch e := e.(type) {
+		case *ast.Ident:
+			if arg, ok := c.args[e.Name]; ok {
+				for _, imp := range arg.Imports {
+					add(imp)
+				}
+				return ident(arg.Expr)
+			}
+			if c.typeParams[e.Name] || !c.declaredInPackage(e) {
+				return ident(e.Name)
+			}
+			add(model.Import{Name: c.pkg.Name, Path: c.pkg.Path})
+			return selector(ident(c.pkg.Name), e.Name)
+		case *ast.SelectorExpr:
+			if x, ok := e.X.(*ast.Ident); ok {
+				add(model.Import{Name: x.Name, Path: c.importPathOf(x.Name)})
+				return selector(ident(x.Name), e.Sel.Name)
+			}
+		}
+		return nil
+	})
+
+	return model.TypeRef{
+		Expr:    exprString(qualified),
+		Imports: imports,
+	}
+}
+
+// declaredInPackage reports if an identifier refers to something declared at
+// the top level of the package, rather than a predeclared type or constant
+func (c *typeContext) declaredInPackage(id *ast.Ident) bool {
+	if id.Obj != nil {
+		return true
+	}
+	if types.Universe.Lookup(id.Name) == nil {
+		return true
+	}
+	p, err := c.loader.loadDir(c.pkg.Dir, c.pkg.Name, strings.HasSuffix(c.file.path, "_test.go"))
+	if err != nil {
+		return false
+	}
+	_, spec := p.lookupType(id.Name)
+	return spec != nil
+}
+
+// importPathOf returns the import path of the package imported as name
+func (c *typeContext) importPathOf(name string) string {
+	srcDir := filepath.Dir(overlayKey(c.file.path))
+	var candidates []string
+	for _, imp := range c.file.astFile.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return importPath
+			}
+			continue
+		}
+		if guessPackageName(importPath) == name {
+			return importPath
+		}
+		candidates = append(candidates, importPath)
+	}
+	// The package name may differ from the last element of the path
+	for _, importPath := range candidates {
+		p, err := c.loader.importPackage(importPath, srcDir)
+		if err == nil && p.name == name {
+			return importPath
+		}
+	}
+	return ""
+}
+
+// guessPackageName guesses the name of a package from its import path, e.g.
+// yaml for gopkg.in/yaml.v3 and chi for github.com/go-chi/chi/v5
+func guessPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") && importPath != base {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			base = path.Base(path.Dir(importPath))
+		}
+	}
+	if i := strings.Index(base, ".v"); i > 0 {
+		base = base[:i]
+	}
+	return strings.TrimPrefix(base, "go-")
+}
+
+// mapTypeExpr returns a copy of a type expression where fn has been applied
+// to the nested expressions, outermost first. Expressions that fn returns nil
+// for are copied and their children visited.
+func mapTypeExpr(expr ast.Expr, fn func(ast.Expr) ast.Expr) ast.Expr {
+	if expr == nil {
+		return nil
+	}
+	if mapped := fn(expr); mapped != nil {
+		return mapped
+	}
+	m := func(e ast.Expr) ast.Expr { return mapTypeExpr(e, fn) }
+	fields := func(list *ast.FieldList) *ast.FieldList {
+		if list == nil {
+			return nil
+		}
+		out := &ast.FieldList{List: make([]*ast.Field, len(list.List))}
+		for i, f := range list.List {
+			var names []*ast.Ident
+			for _, n := range f.Names {
+				names = append(names, ident(n.Name))
+			}
+			out.List[i] = &ast.Field{Names: names, Type: m(f.Type), Tag: copyTag(f.Tag)}
+		}
+		return out
+	}
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return ident(e.Name)
+	case *ast.BasicLit:
+		return &ast.BasicLit{Kind: e.Kind, Value: e.Value}
+	case *ast.SelectorExpr:
+		return &ast.SelectorExpr{X: m(e.X), Sel: ident(e.Sel.Name)}
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: m(e.X)}
+	case *ast.ParenExpr:
+		return &ast.ParenExpr{X: m(e.X)}
+	case *ast.Ellipsis:
+		return &ast.Ellipsis{Elt: m(e.Elt)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: m(e.Len), Elt: m(e.Elt)}
+	case *ast.MapType:
+		return &ast.MapType{Key: m(e.Key), Value: m(e.Value)}
+	case *ast.ChanType:
+		return &ast.ChanType{Dir: e.Dir, Value: m(e.Value)}
+	case *ast.FuncType:
+		return &ast.FuncType{Params: fields(e.Params), Results: fields(e.Results)}
+	case *ast.StructType:
+		return &ast.StructType{Fields: fields(e.Fields)}
+	case *ast.InterfaceType:
+		return &ast.InterfaceType{Methods: fields(e.Methods)}
+	case *ast.IndexExpr:
+		return &ast.IndexExpr{X: m(e.X), Index: m(e.Index)}
+	case *ast.IndexListExpr:
+		indices := make([]ast.Expr, len(e.Indices))
+		for i, index := range e.Indices {
+			indices[i] = m(index)
+		}
+		return &ast.IndexListExpr{X: m(e.X), Indices: indices}
+	case *ast.UnaryExpr:
+		return &ast.UnaryExpr{Op: e.Op, X: m(e.X)}
+	case *ast.BinaryExpr:
+		return &ast.BinaryExpr{X: m(e.X), Op: e.Op, Y: m(e.Y)}
+	}
+	return astcopy.Expr(expr)
+}
+
+// copyTag copies a struct tag, the syntax trees of parsed files are shared and
+// must not be modified
+func copyTag(tag *ast.BasicLit) *ast.BasicLit {
+	if tag == nil {
+		return nil
+	}
+	return &ast.BasicLit{Kind: tag.Kind, Value: tag.Value}
+}
+
+// exprString formats an expression without positions
+func exprString(expr ast.Expr) string {
+	clearPositions(expr)
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), expr); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// docText returns the text of a doc comment. Lines of block comments are
+// trimmed since they are usually indented to line up with the markers.
+func docText(doc *ast.CommentGroup) string {
+	text := doc.Text()
+	if text == "" || !strings.HasPrefix(doc.List[0].Text, "/*") {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// position converts a position in the file to a model position, with an
+// absolute path
+func (f *file) position(pos token.Pos) model.Position {
+	p := f.fset.Position(pos)
+	return model.Position{
+		Filename: overlayKey(f.path),
+		Line:     p.Line,
+		Column:   p.Column,
+		Offset:   p.Offset,
+	}
+}
@@ -0,0 +1,133 @@
+package mockgen
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+func TestParseInterface(t *testing.T) {
+	other := "package other\n\ntype Option int\n\ntype Thing struct{}\n\ntype Getter[T any] interface {\n\tGet() T\n}\n"
+
+	tests := []struct {
+		name string
+		src  string
+		// methods are the methods of Store as in the source, with unnamed
+		// parameters named like in mocks
+		methods    []string
+		typeParams []model.Param
+		imports    []model.Import
+	}{
+		{
+			name: "embedded",
+			src: "package store\n\nimport \"io\"\n\n" +
+				"type Reader interface {\n\tRead(p []byte) (int, error)\n}\n\n" +
+				"type Store interface {\n\tReader\n\tio.Closer\n\terror\n\tGet() int\n}\n",
+			methods: []string{"Read(p []byte) (int, error)", "Close() error", "Error() string", "Get() int"},
+		},
+		{
+			name: "embedded twice",
+			src: "package store\n\nimport \"io\"\n\n" +
+				"type Store interface {\n\tio.ReadCloser\n\tio.Closer\n\tClose() error\n}\n",
+			methods: []string{"Read(p []byte) (n int, err error)", "Close() error"},
+		},
+		{
+			name: "generic",
+			src: "package store\n\n" +
+				"type Getter[T any] interface {\n\tGet(key string) T\n}\n\n" +
+				"type Store[K comparable, V any] interface {\n\tGetter[V]\n\tPut(key K, value V)\n}\n",
+			methods: []string{"Get(key string) V", "Put(key K, value V)"},
+			typeParams: []model.Param{
+				{Name: "K", Type: model.TypeRef{Expr: "comparable"}},
+				{Name: "V", Type: model.TypeRef{Expr: "any"}},
+			},
+		},
+		{
+			name: "generic from another package",
+			src: "package store\n\nimport \"example.com/m/other\"\n\n" +
+				"type Store interface {\n\tother.Getter[other.Thing]\n}\n",
+			methods: []string{"Get() other.Thing"},
+			imports: []model.Import{{Name: "other", Path: "example.com/m/other"}},
+		},
+		{
+			name: "qualified types",
+			src: "package store\n\nimport (\n\t\"context\"\n\n\t\"example.com/m/other\"\n)\n\n" +
+				"type Key string\n\n" +
+				"type Store interface {\n\tFind(context.Context, Key) (map[Key]*other.Thing, error)\n}\n",
+			methods: []string{"Find(arg1 context.Context, arg2 store.Key) (map[store.Key]*other.Thing, error)"},
+			imports: []model.Import{
+				{Name: "context", Path: "context"},
+				{Name: "other", Path: "example.com/m/other"},
+				{Name: "store", Path: "example.com/m/store"},
+			},
+		},
+		{
+			name: "variadic",
+			src: "package store\n\nimport \"example.com/m/other\"\n\n" +
+				"type Store interface {\n\tLog(format string, args ...interface{})\n\tOpen(name string, opts ...other.Option) error\n\tJoin(...[]string) []string\n}\n",
+			methods: []string{
+				"Log(format string, args ...interface{})",
+				"Open(name string, opts ...other.Option) error",
+				"Join(arg1 ...[]string) []string",
+			},
+			imports: []model.Import{{Name: "other", Path: "example.com/m/other"}},
+		},
+		{
+			name: "named imports",
+			src: "package store\n\nimport (\n\th \"net/http\"\n\t_ \"embed\"\n\t. \"strings\"\n\t\"example.com/m/other\"\n)\n\n" +
+				"var _ = NewReader\n\n" +
+				"type Store interface {\n\tDo(*h.Request) (*h.Response, error)\n\tThings() []other.Thing\n}\n",
+			methods: []string{
+				"Do(arg1 *h.Request) (*h.Response, error)",
+				"Things() []other.Thing",
+			},
+			imports: []model.Import{
+				{Name: "other", Path: "example.com/m/other"},
+				{Name: "h", Path: "net/http"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"go.mod":         "module example.com/m\n",
+				"other/other.go": other,
+				"store/store.go": test.src,
+			})
+
+			l := newLoader(nil, &nopLogger{})
+			f, err := l.openFile(filepath.Join(dir, "store", "store.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			iface, err := l.parseInterface(f, f.lookupType("Store"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var methods []string
+			for _, m := range iface.Methods {
+				signature := m.Name + "(" + argList(m) + ")"
+				if results := resultList(m); results != "" {
+					signature += " " + results
+				}
+				methods = append(methods, signature)
+			}
+			if !reflect.DeepEqual(methods, test.methods) {
+				t.Errorf("got methods\n%q\nwant\n%q", methods, test.methods)
+			}
+			if !reflect.DeepEqual(iface.TypeParams, test.typeParams) {
+				t.Errorf("got type parameters %+v, want %+v", iface.TypeParams, test.typeParams)
+			}
+			if imports := iface.Imports(); !reflect.DeepEqual(imports, test.imports) {
+				t.Errorf("got imports %+v, want %+v", imports, test.imports)
+			}
+			if want := (model.Package{Name: "store", Path: "example.com/m/store", Dir: filepath.Join(dir, "store")}); iface.Package != want {
+				t.Errorf("got package %+v, want %+v", iface.Package, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package mockgen
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lindell/mockay/astcopy"
+)
+
+// printFile formats a generated file. The go/printer does not handle nodes
+// without positions well, doc comments end up on the wrong lines and
+// declarations are not separated. The file is therefore printed without any
+// comments first, parsed again to get real positions and finally the comments
+// are inserted at the position of the node they document.
+func printFile(file *ast.File) ([]byte, error) {
+	cp := astcopy.File(file)
+	clearPositions(cp)
+	docs := detachDocs(cp)
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), cp); err != nil {
+		return nil, err
+	}
+	src := buf.Bytes()
+
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	nodes := documentable(parsed)
+	if len(nodes) != len(docs) {
+		return nil, errors.New("could not place comments in generated code")
+	}
+
+	type insertion struct {
+		offset int
+		text   string
+	}
+	var inserts []insertion
+	topLevel := map[ast.Node]bool{}
+	for _, d := range parsed.Decls {
+		topLevel[d] = true
+	}
+	for i, n := range nodes {
+		if docs[i] == nil && !topLevel[n] {
+			continue
+		}
+		offset := fset.Position(n.Pos()).Offset
+		lineStart := bytes.LastIndexByte(src[:offset], '\n') + 1
+		indent := string(src[lineStart:offset])
+
+		var text strings.Builder
+		if topLevel[n] {
+			text.WriteString("\n")
+		}
+		if docs[i] != nil {
+			for _, c := range docs[i].List {
+				text.WriteString(indent + c.Text + "\n")
+			}
+		}
+		inserts = append(inserts, insertion{offset: lineStart, text: text.String()})
+	}
+	sort.SliceStable(inserts, func(i, j int) bool {
+		return inserts[i].offset < inserts[j].offset
+	})
+
+	var out bytes.Buffer
+	last := 0
+	for _, in := range inserts {
+		out.Write(src[last:in.offset])
+		out.WriteString(in.text)
+		last = in.offset
+	}
+	out.Write(src[last:])
+
+	return format.Source(out.Bytes())
+}
+
+// documentable returns all nodes that may have a doc comment, in the order
+// they are visited
+func documentable(file *ast.File) []ast.Node {
+	var nodes []ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.GenDecl, *ast.FuncDecl, *ast.Field:
+			nodes = append(nodes, n)
+		}
+		return true
+	})
+	return nodes
+}
+
+// detachDocs removes all doc comments from the file and returns them in the
+// same order as documentable returns their nodes
+func detachDocs(file *ast.File) []*ast.CommentGroup {
+	var docs []*ast.CommentGroup
+	for _, n := range documentable(file) {
+		switch n := n.(type) {
+		case *ast.GenDecl:
+			docs = append(docs, n.Doc)
+			n.Doc = nil
+		case *ast.FuncDecl:
+			docs = append(docs, n.Doc)
+			n.Doc = nil
+		case *ast.Field:
+			docs = append(docs, n.Doc)
+			n.Doc, n.Comment = nil, nil
+		}
+	}
+	file.Comments = nil
+	return docs
+}
+
+var posType = reflect.TypeOf(token.NoPos)
+
+// clearPositions removes the positions of all nodes, except the ellipsis of
+// calls which carries meaning. Nodes copied from a parsed file would otherwise
+// refer to lines in another file. Inline struct and
+// interface types without documented fields are given a valid, identical,
+// opening and closing position to make them print on one line when possible.
+func clearPositions(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		v := reflect.ValueOf(n)
+		if n == nil || v.Kind() != reflect.Ptr || v.IsNil() {
+			return false
+		}
+		ellipsis := false
+		if c, ok := n.(*ast.CallExpr); ok {
+			ellipsis = c.Ellipsis.IsValid()
+		}
+		v = v.Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Type() == posType {
+				v.Field(i).SetInt(int64(token.NoPos))
+			}
+		}
+		if ellipsis {
+			n.(*ast.CallExpr).Ellipsis = 1
+		}
+		return true
+	})
+	declared := map[ast.Expr]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok {
+			declared[spec.Type] = true
+		}
+		var list *ast.FieldList
+		switch n := n.(type) {
+		case *ast.StructType:
+			list = n.Fields
+		case *ast.InterfaceType:
+			list = n.Methods
+		}
+		if list != nil && !declared[n.(ast.Expr)] && !hasDocs(list) {
+			list.Opening, list.Closing = 1, 1
+		}
+		return true
+	})
+}
+
+func hasDocs(list *ast.FieldList) bool {
+	for _, f := range list.List {
+		if f.Doc != nil {
+			return true
+		}
+	}
+	return false
+}
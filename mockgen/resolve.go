@@ -0,0 +1,255 @@
+package mockgen
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxResolveDepth limits how many type declarations are followed, e.g. when
+// an interface is declared as another named type
+const maxResolveDepth = 10
+
+// pathAt returns the nodes enclosing pos, the outermost first
+func (f *file) pathAt(pos token.Pos) []ast.Node {
+	var path []ast.Node
+	ast.Inspect(f.astFile, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || pos > n.End() {
+			return false
+		}
+		path = append(path, n)
+		return true
+	})
+	return path
+}
+
+// inlineInterfaceAt finds an anonymous interface type at pos, e.g. the type of
+// a parameter or field. A type spec is created for it with a name derived from
+// where it is used.
+func (f *file) inlineInterfaceAt(pos token.Pos) *ast.TypeSpec {
+	path := f.pathAt(pos)
+	for i := len(path) - 1; i >= 0; i-- {
+		iface, ok := path[i].(*ast.InterfaceType)
+		if !ok {
+			continue
+		}
+
+		var field *ast.Field
+		if i > 0 {
+			field, _ = path[i-1].(*ast.Field)
+		}
+		spec := &ast.TypeSpec{
+			Name: ident(inlineInterfaceName(path[:i], field)),
+			Type: iface,
+		}
+		if field != nil {
+			spec.Doc = field.Doc
+		}
+		return spec
+	}
+	return nil
+}
+
+// inlineInterfaceName creates a name for an anonymous interface from the
+// name of the enclosing declaration and the name of the field or parameter
+// it is the type of, e.g. RunLogger for the parameter logger of func Run
+func inlineInterfaceName(path []ast.Node, field *ast.Field) string {
+	var name string
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			name = exported(n.Name.Name)
+		case *ast.TypeSpec:
+			name = exported(n.Name.Name)
+		case *ast.ValueSpec:
+			name = exported(n.Names[0].Name)
+		}
+	}
+	if field != nil && len(field.Names) > 0 && field.Names[0].Name != "_" {
+		return name + exported(field.Names[0].Name)
+	}
+	if name == "" {
+		return "Interface"
+	}
+	return name + "Interface"
+}
+
+// exported returns name with the first letter in upper case
+func exported(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// resolveAt finds the interface declaration referred to by the identifier at
+// pos, e.g. the type of a parameter or field
+func (l *loader) resolveAt(f *file, pos token.Pos) (*file, *ast.TypeSpec, error) {
+	path := f.pathAt(pos)
+
+	var expr ast.Expr
+	for i := len(path) - 1; i >= 0 && expr == nil; i-- {
+		id, ok := path[i].(*ast.Ident)
+		if !ok {
+			continue
+		}
+		expr = id
+		if i == 0 {
+			break
+		}
+		// The cursor may be on the name of a parameter, field or variable
+		// instead of its type
+		switch parent := path[i-1].(type) {
+		case *ast.SelectorExpr:
+			expr = parent
+		case *ast.Field:
+			if parent.Type != id {
+				expr = parent.Type
+			}
+		case *ast.ValueSpec:
+			if parent.Type != nil {
+				expr = parent.Type
+			}
+		}
+	}
+	if expr == nil {
+		return nil, nil, &NotFoundError{}
+	}
+
+	return l.resolveExpr(f, expr, 0)
+}
+
+// resolveExpr finds the interface declaration of a type expression used in f
+func (l *loader) resolveExpr(f *file, expr ast.Expr, depth int) (*file, *ast.TypeSpec, error) {
+	if depth > maxResolveDepth {
+		return nil, nil, errors.New("too many type declarations to follow")
+	}
+
+	var declFile *file
+	var spec *ast.TypeSpec
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if expr.Obj != nil && expr.Obj.Kind == ast.Typ {
+			// Declared in the same file, possibly inside a function
+			if s, ok := expr.Obj.Decl.(*ast.TypeSpec); ok {
+				declFile, spec = f, s
+				break
+			}
+		}
+		p, err := l.loadDir(filepath.Dir(f.path), f.astFile.Name.Name, strings.HasSuffix(f.path, "_test.go"))
+		if err != nil {
+			return nil, nil, err
+		}
+		declFile, spec = p.lookupType(expr.Name)
+		if spec == nil {
+			return nil, nil, &NotFoundError{Name: expr.Name}
+		}
+	case *ast.SelectorExpr:
+		pkgIdent, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return nil, nil, &NotFoundError{}
+		}
+		// A variable declared in the file, e.g. the receiver of a method
+		// call, is not a package
+		var p *pkg
+		if pkgIdent.Obj == nil {
+			var err error
+			if p, err = l.importedPackage(f, pkgIdent.Name); err != nil {
+				return nil, nil, err
+			}
+		}
+		if p != nil {
+			declFile, spec = p.lookupType(expr.Sel.Name)
+		}
+		if spec == nil {
+			return nil, nil, &NotFoundError{Name: pkgIdent.Name + "." + expr.Sel.Name}
+		}
+	case *ast.IndexExpr:
+		return l.resolveExpr(f, expr.X, depth)
+	case *ast.IndexListExpr:
+		return l.resolveExpr(f, expr.X, depth)
+	case *ast.ParenExpr:
+		return l.resolveExpr(f, expr.X, depth)
+	default:
+		return nil, nil, &NotFoundError{}
+	}
+
+	switch spec.Type.(type) {
+	case *ast.InterfaceType:
+		return declFile, spec, nil
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.IndexListExpr, *ast.ParenExpr:
+		return l.resolveExpr(declFile, spec.Type, depth+1)
+	}
+	return nil, nil, &UnsupportedTypeError{
+		Name:     spec.Name.Name,
+		Position: declFile.position(spec.Name.Pos()),
+		Reason:   "it is not an interface",
+	}
+}
+
+// packageCandidates returns the interfaces declared in the package of f
+func (l *loader) packageCandidates(f *file) []Candidate {
+	p, err := l.loadDir(filepath.Dir(f.path), f.astFile.Name.Name, strings.HasSuffix(f.path, "_test.go"))
+	if err != nil {
+		return nil
+	}
+	var candidates []Candidate
+	for _, pf := range p.files {
+		for _, spec := range pf.interfaces() {
+			if len(candidates) == maxCandidates {
+				return candidates
+			}
+			candidates = append(candidates, Candidate{
+				Name:     spec.Name.Name,
+				Position: pf.position(spec.Name.Pos()),
+			})
+		}
+	}
+	return candidates
+}
+
+// importedPackage loads the package imported as name in f, it is nil if no
+// package is imported as name. Imports without a name are only loaded if
+// the last element of their path could be the name of the package.
+func (l *loader) importedPackage(f *file, name string) (*pkg, error) {
+	srcDir := filepath.Dir(overlayKey(f.path))
+	for _, imp := range f.astFile.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return l.importPackage(importPath, srcDir)
+			}
+			continue
+		}
+		if !couldBeNamed(importPath, name) {
+			continue
+		}
+
+		p, err := l.importPackage(importPath, srcDir)
+		if err != nil {
+			continue
+		}
+		if p.name == name {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// couldBeNamed reports if the package with the import path could be named
+// name, which is contained in the last element of the path that is not a
+// major version, ignoring case, as in gopkg.in/yaml.v3 and go-sqlite3
+func couldBeNamed(importPath, name string) bool {
+	last := path.Base(importPath)
+	if len(last) > 1 && last[0] == 'v' && strings.Trim(last[1:], "0123456789") == "" {
+		last = path.Base(path.Dir(importPath))
+	}
+	return strings.Contains(strings.ToLower(last), strings.ToLower(name))
+}
@@ -0,0 +1,72 @@
+package mockgen
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescribeUsage(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":        "module example.com/m\n",
+		"log/log.go":    "package log\n\ntype Logger interface {\n\tLog(msg string)\n}\n",
+		"go-store/s.go": "package store\n\ntype Store interface {\n\tGet() int\n}\n",
+		"use/use.go": "package use\n\nimport (\n\t\"example.com/m/go-store\"\n\t\"example.com/m/log\"\n)\n\n" +
+			"func Run(l log.Logger, s store.Store) {\n\tl.Log(\"run\")\n}\n",
+	})
+	path := filepath.Join(dir, "use", "use.go")
+
+	tests := []struct {
+		name string
+		pos  Position
+		want string
+	}{
+		{name: "qualified type", pos: Position{X: 8, Y: 14}, want: "Logger"},
+		{name: "parameter", pos: Position{X: 8, Y: 10}, want: "Logger"},
+		{name: "package with another name", pos: Position{X: 8, Y: 32}, want: "Store"},
+		{name: "method call on a variable", pos: Position{X: 9, Y: 2}},
+		{name: "method of a variable", pos: Position{X: 9, Y: 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ifaces, err := New().Describe(context.Background(), Request{Path: path, Position: &test.pos})
+			if test.want == "" {
+				var notFound *NotFoundError
+				if !errors.As(err, &notFound) || !errors.Is(err, ErrInterfaceNotFound) {
+					t.Fatalf("got %v, %v, want an interface not found error", ifaces, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ifaces[0].Name != test.want {
+				t.Errorf("got %s, want %s", ifaces[0].Name, test.want)
+			}
+		})
+	}
+}
+
+func TestCouldBeNamed(t *testing.T) {
+	tests := []struct {
+		path string
+		name string
+		want bool
+	}{
+		{path: "io", name: "io", want: true},
+		{path: "net/http", name: "http", want: true},
+		{path: "gopkg.in/yaml.v3", name: "yaml", want: true},
+		{path: "github.com/mattn/go-sqlite3", name: "sqlite3", want: true},
+		{path: "github.com/jackc/pgx/v5", name: "pgx", want: true},
+		{path: "github.com/google/UUID", name: "uuid", want: true},
+		{path: "net/http", name: "io", want: false},
+		{path: "github.com/jackc/pgx/v5", name: "v5", want: false},
+	}
+	for _, test := range tests {
+		if got := couldBeNamed(test.path, test.name); got != test.want {
+			t.Errorf("couldBeNamed(%q, %q) = %t, want %t", test.path, test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package mockgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// errTemplateMerge is returned when a mock rendered from a template would be
+// merged into an existing file
+var errTemplateMerge = errors.New("mocks rendered from templates can not be merged into existing files")
+
+// TemplateFuncs returns the functions available in templates
+//
+//	camel      converts a name to camelCase
+//	snake      converts a name to snake_case
+//	zeroValue  returns the zero value of a model.TypeRef
+//	argList    returns the parameters of a model.Method, with names
+//	argNames   returns the names of the parameters of a model.Method, as
+//	           arguments of a call
+//	resultList returns the results of a model.Method
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"camel":      camelCase,
+		"snake":      snakeCase,
+		"zeroValue":  zeroValue,
+		"argList":    argList,
+		"argNames":   argNames,
+		"resultList": resultList,
+	}
+}
+
+// ParseTemplate reads a template of a mock file from path. The template is
+// executed with a *model.Interface.
+func ParseTemplate(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(TemplateFuncs()).Parse(string(content))
+}
+
+// renderTemplate executes the template with the interface and formats the
+// result. Syntax errors in the result are reported at the lines of the
+// template that produced them.
+func renderTemplate(tmpl *template.Template, iface *model.Interface) (*ast.File, []byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, iface); err != nil {
+		return nil, nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, nil, templateSyntaxError(tmpl, iface, err)
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, src, nil
+}
+
+// templateSyntaxError executes the template again with line directives after
+// each newline of its text, which makes the errors found in the output refer
+// to the lines of the template
+func templateSyntaxError(tmpl *template.Template, iface *model.Interface, formatErr error) error {
+	lined := template.New(tmpl.Name()).Funcs(TemplateFuncs())
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		tree := t.Tree.Copy()
+		addLineDirectives(tree, tree.Root)
+		if _, err := lined.AddParseTree(t.Name(), tree); err != nil {
+			return formatErr
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := lined.Execute(&buf, iface); err != nil {
+		return formatErr
+	}
+	_, err := format.Source(buf.Bytes())
+	if err == nil {
+		return formatErr
+	}
+	return fmt.Errorf("template produced invalid Go code: %w", err)
+}
+
+// addLineDirectives adds a //line directive after each newline of the text
+// nodes, with the line of the template the following text is on
+func addLineDirectives(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addLineDirectives(tree, child)
+		}
+	case *parse.IfNode:
+		addLineDirectives(tree, n.List)
+		addLineDirectives(tree, n.ElseList)
+	case *parse.RangeNode:
+		addLineDirectives(tree, n.List)
+		addLineDirectives(tree, n.ElseList)
+	case *parse.WithNode:
+		addLineDirectives(tree, n.List)
+		addLineDirectives(tree, n.ElseList)
+	case *parse.TextNode:
+		location, _ := tree.ErrorContext(n)
+		var line int
+		if i := strings.LastIndexByte(location, ':'); i > 0 {
+			if j := strings.LastIndexByte(location[:i], ':'); j >= 0 {
+				fmt.Sscan(location[j+1:i], &line)
+			}
+		}
+		if line == 0 {
+			return
+		}
+
+		var text strings.Builder
+		for _, c := range string(n.Text) {
+			text.WriteRune(c)
+			if c == '\n' {
+				line++
+				fmt.Fprintf(&text, "//line %s:%d\n", tree.ParseName, line)
+			}
+		}
+		n.Text = []byte(text.String())
+	}
+}
+
+// zeroValue returns the zero value of a type as Go source
+func zeroValue(ref model.TypeRef) string {
+	expr, err := parser.ParseExpr(ref.Expr)
+	if err != nil {
+		return "*new(" + ref.Expr + ")"
+	}
+	switch e := expr.(type) {
+	case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+		return "nil"
+	case *ast.ArrayType:
+		if e.Len == nil {
+			return "nil"
+		}
+		return ref.Expr + "{}"
+	case *ast.StructType:
+		return ref.Expr + "{}"
+	case *ast.Ident:
+		switch e.Name {
+		case "error", "any":
+			return "nil"
+		case "bool":
+			return "false"
+		case "string":
+			return `""`
+		case "int", "int8", "int16", "int32", "int64",
+			"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+			"float32", "float64", "complex64", "complex128", "byte", "rune":
+			return "0"
+		}
+	}
+	// Named types may have any underlying type
+	return "*new(" + ref.Expr + ")"
+}
+
+// paramName returns the name of the i:th parameter, or a generated one if
+// it does not have a usable name
+func paramName(p model.Param, i int) string {
+	if p.Name == "" || p.Name == "_" {
+		return fmt.Sprintf("arg%d", i+1)
+	}
+	return p.Name
+}
+
+func argList(m model.Method) string {
+	params := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		typ := p.Type.Expr
+		if m.Variadic && i == len(m.Params)-1 {
+			typ = "..." + typ
+		}
+		params[i] = paramName(p, i) + " " + typ
+	}
+	return strings.Join(params, ", ")
+}
+
+func argNames(m model.Method) string {
+	names := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		names[i] = paramName(p, i)
+		if m.Variadic && i == len(m.Params)-1 {
+			names[i] += "..."
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func resultList(m model.Method) string {
+	results := make([]string, len(m.Results))
+	named := false
+	for i, r := range m.Results {
+		results[i] = r.Type.Expr
+		if r.Name != "" {
+			named = true
+			results[i] = r.Name + " " + r.Type.Expr
+		}
+	}
+	if len(results) == 1 && !named {
+		return results[0]
+	}
+	if len(results) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(results, ", ") + ")"
+}
@@ -0,0 +1,107 @@
+package mockgen
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// storeSource declares an interface with several methods, so that ranges over
+// them in templates are executed more than once
+const storeSource = "package store\n\ntype Store interface {\n\tGet(key string) (int, error)\n\tPut(key string, value int)\n\tLen() int\n}\n"
+
+func TestRenderTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":         "module example.com/m\n",
+		"store/store.go": storeSource,
+		"fake.tmpl": "package {{.Package.Name}}\n\n" +
+			"// Fake{{.Name}} does nothing\n" +
+			"type Fake{{.Name}} struct{}\n" +
+			"{{range .Methods}}\n" +
+			"func (Fake{{$.Name}}) {{.Name}}({{argList .}}) {{resultList .}} {\n" +
+			"{{- if .Results}}\n" +
+			"\treturn {{range $i, $r := .Results}}{{if $i}}, {{end}}{{zeroValue $r.Type}}{{end}}\n" +
+			"{{- end}}\n" +
+			"}\n" +
+			"{{end}}",
+	})
+	tmpl, err := ParseTemplate(filepath.Join(dir, "fake.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(WithTemplate(tmpl), WithVerify(), WithLayout(Layout{Package: TestPackage}))
+	files, err := g.GenerateFiles(context.Background(), Request{Path: filepath.Join(dir, "store", "store.go"), Name: "Store"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+	src := string(files[0].Source)
+	for _, want := range []string{
+		"package store\n",
+		"// FakeStore does nothing\ntype FakeStore struct{}\n",
+		"func (FakeStore) Get(key string) (int, error) {\n\treturn 0, nil\n}\n",
+		"func (FakeStore) Put(key string, value int) {\n}\n",
+		"func (FakeStore) Len() int {\n\treturn 0\n}\n",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("the mock does not contain %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestTemplateErrorLines(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		// want is contained in the error, the line of the template it is on
+		want string
+	}{
+		{
+			name: "invalid code",
+			tmpl: "package mock\n\ntype Fake{{.Name}} struct{}\n\nfunc (Fake{{.Name}} {\n}\n",
+			want: "fake.tmpl:5:",
+		},
+		{
+			name: "invalid code after a range",
+			tmpl: "package mock\n\ntype Fake{{.Name}} struct{}\n{{range .Methods}}\nfunc (Fake{{$.Name}}) {{.Name}}() {}\n{{end}}\nvar = 1\n",
+			want: "fake.tmpl:7:",
+		},
+		{
+			name: "invalid code in a range",
+			tmpl: "package mock\n\ntype Fake{{.Name}} struct{}\n{{range .Methods}}\nfunc (Fake{{$.Name}}) {{.Name}}() {\n\treturn return\n}\n{{end}}\n",
+			want: "fake.tmpl:6:",
+		},
+		{
+			name: "execution",
+			tmpl: "package mock\n\n{{.Missing}}\n",
+			want: "fake.tmpl:3:",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"store.go":  storeSource,
+				"fake.tmpl": test.tmpl,
+			})
+			tmpl, err := ParseTemplate(filepath.Join(dir, "fake.tmpl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g := New(WithTemplate(tmpl))
+			_, err = g.GenerateFiles(context.Background(), Request{Path: filepath.Join(dir, "store.go"), Name: "Store"})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("the error %q is not reported at %s", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package mockgen
+
+import (
+	"go/ast"
+	"go/parser"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// checkExported returns an *UnexportedError if the interface can not be
+// implemented by a mock in another package than its own, as it has
+// unexported methods or refers to unexported types
+func (f *Generator) checkExported(iface *model.Interface) error {
+	err := &UnexportedError{Interface: iface.Name, Position: iface.Position, Package: iface.Package.Name}
+	for _, p := range iface.TypeParams {
+		err.Uses = append(err.Uses, f.unexportedTypes(iface, "", iface.Position, p.Type)...)
+	}
+	for _, m := range iface.Methods {
+		if !ast.IsExported(m.Name) {
+			err.Uses = append(err.Uses, UnexportedUse{Method: m.Name, Position: m.Position})
+		}
+		seen := map[string]bool{}
+		for _, ref := range methodTypes(m) {
+			for _, use := range f.unexportedTypes(iface, m.Name, m.Position, ref) {
+				if !seen[use.Type] {
+					seen[use.Type] = true
+					err.Uses = append(err.Uses, use)
+				}
+			}
+		}
+	}
+	if len(err.Uses) == 0 {
+		return nil
+	}
+	return err
+}
+
+// unexportedTypes returns the unexported types that the type, used by the
+// method of the interface, refers to in the order they appear in it
+func (f *Generator) unexportedTypes(iface *model.Interface, method string, position model.Position, ref model.TypeRef) []UnexportedUse {
+	expr, err := parser.ParseExpr(ref.Expr)
+	if err != nil {
+		return nil
+	}
+	var uses []UnexportedUse
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkgName, ok := sel.X.(*ast.Ident)
+		if !ok || ast.IsExported(sel.Sel.Name) {
+			return false
+		}
+		use := UnexportedUse{
+			Method:   method,
+			Position: position,
+			Type:     pkgName.Name + "." + sel.Sel.Name,
+		}
+		for _, imp := range ref.Imports {
+			if imp.Name == pkgName.Name {
+				use.TypePosition = f.typePosition(iface, imp.Path, sel.Sel.Name)
+			}
+		}
+		uses = append(uses, use)
+		return false
+	})
+	return uses
+}
+
+// typePosition returns the position of the declaration of the type name in
+// the package imported with path, zero if it is not found. The package of the
+// interface is loaded with its test files, which may declare the type.
+func (f *Generator) typePosition(iface *model.Interface, path, name string) model.Position {
+	var p *pkg
+	var err error
+	if path == iface.Package.Path {
+		p, err = f.loader.loadDir(iface.Package.Dir, iface.Package.Name, true)
+	} else {
+		p, err = f.loader.importPackage(path, iface.Package.Dir)
+	}
+	if err != nil {
+		return model.Position{}
+	}
+	file, spec := p.lookupType(name)
+	if spec == nil {
+		return model.Position{}
+	}
+	return positionOf(file.path, file.fset.Position(spec.Name.Pos()))
+}
+
+// methodTypes returns the types of the parameters and results of the method
+func methodTypes(m model.Method) []model.TypeRef {
+	var refs []model.TypeRef
+	for _, p := range m.Params {
+		refs = append(refs, p.Type)
+	}
+	for _, r := range m.Results {
+		refs = append(refs, r.Type)
+	}
+	return refs
+}
@@ -0,0 +1,108 @@
+package mockgen
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+func TestUnexportedError(t *testing.T) {
+	tests := []struct {
+		name  string
+		iface string
+		// uses are the expected uses, their positions only have lines
+		uses []UnexportedUse
+	}{
+		{name: "exported", iface: "type Store interface {\n\tGet(key Key) Item\n}"},
+		{
+			name:  "unexported method",
+			iface: "type Store interface {\n\tGet() int\n\tput(int)\n}",
+			uses:  []UnexportedUse{{Method: "put", Position: model.Position{Line: 12}}},
+		},
+		{
+			name:  "unexported types",
+			iface: "type Store interface {\n\tGet(k key) (map[key][]*item, error)\n\tLen() int\n}",
+			uses: []UnexportedUse{
+				{Method: "Get", Position: model.Position{Line: 11}, Type: "store.key", TypePosition: model.Position{Line: 3}},
+				{Method: "Get", Position: model.Position{Line: 11}, Type: "store.item", TypePosition: model.Position{Line: 4}},
+			},
+		},
+		{
+			name:  "unexported method with unexported types",
+			iface: "type Store interface {\n\tget(key) item\n}",
+			uses: []UnexportedUse{
+				{Method: "get", Position: model.Position{Line: 11}},
+				{Method: "get", Position: model.Position{Line: 11}, Type: "store.key", TypePosition: model.Position{Line: 3}},
+				{Method: "get", Position: model.Position{Line: 11}, Type: "store.item", TypePosition: model.Position{Line: 4}},
+			},
+		},
+		{
+			name:  "type parameters",
+			iface: "type Store[T constraint] interface {\n\tGet() T\n}",
+			uses:  []UnexportedUse{{Position: model.Position{Line: 10}, Type: "store.constraint", TypePosition: model.Position{Line: 5}}},
+		},
+		{
+			name:  "declared in a test file",
+			iface: "type Store interface {\n\tGet() fixture\n}",
+			uses:  []UnexportedUse{{Method: "Get", Position: model.Position{Line: 11}, Type: "store.fixture", TypePosition: model.Position{Line: 3}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"go.mod": "module example.com/m\n",
+				"store.go": "package store\n\ntype key string\ntype item struct{}\ntype constraint interface{ ~int }\n\n" +
+					"type Key string\ntype Item struct{}\n\n" + test.iface + "\n",
+				"store_test.go": "package store\n\ntype fixture int\n",
+			})
+			path := filepath.Join(dir, "store.go")
+			for i, use := range test.uses {
+				test.uses[i].Position.Filename = path
+				if use.TypePosition.Line > 0 {
+					test.uses[i].TypePosition.Filename = path
+				}
+				if use.Type == "store.fixture" {
+					test.uses[i].TypePosition.Filename = filepath.Join(dir, "store_test.go")
+				}
+			}
+
+			_, err := New().GenerateFiles(context.Background(), Request{Path: path, Name: "Store"})
+			if test.uses == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnexported) {
+				t.Fatalf("got %v, want %v", err, ErrUnexported)
+			}
+			var unexported *UnexportedError
+			if !errors.As(err, &unexported) {
+				t.Fatalf("got %v, want an *UnexportedError", err)
+			}
+			if unexported.Interface != "Store" || unexported.Package != "store" {
+				t.Errorf("got interface %s in package %s, want Store in store", unexported.Interface, unexported.Package)
+			}
+			// Only the lines of the positions are compared
+			for i := range unexported.Uses {
+				use := &unexported.Uses[i]
+				use.Position.Column, use.Position.Offset = 0, 0
+				use.TypePosition.Column, use.TypePosition.Offset = 0, 0
+			}
+			if !reflect.DeepEqual(unexported.Uses, test.uses) {
+				t.Errorf("got uses\n%+v\nwant\n%+v", unexported.Uses, test.uses)
+			}
+
+			// The mock can be generated in the package of the interface
+			_, err = New(WithVerify(), WithLayout(Layout{Package: TestPackage})).GenerateFiles(context.Background(), Request{Path: path, Name: "Store"})
+			if err != nil {
+				t.Errorf("could not generate the mock in the package of the interface: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package mockgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lindell/mockay/mockgen/model"
+)
+
+// WithVerify makes generated mocks be type checked against the package of
+// the interface before they are returned. Packages are imported from their
+// source, so no compiled packages are needed. A mock that does not type check
+// is reported with a *VerifyError.
+func WithVerify() Option {
+	return func(f *Generator) { f.verify = true }
+}
+
+// stdImporter imports packages of the standard library from source. It is
+// shared by the mocks verified by a generator, the importer is not safe for
+// concurrent use.
+type stdImporter struct {
+	mu       sync.Mutex
+	importer types.Importer
+}
+
+func (i *stdImporter) Import(path string) (*types.Package, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.importer.Import(path)
+}
+
+// sourceImporter imports the packages used by a mock by type checking their
+// source as found by the loader, which sees the files of the overlay
+type sourceImporter struct {
+	loader *loader
+	std    *stdImporter
+	// pkgs are the imported packages by directory, nil while being imported
+	pkgs map[string]*types.Package
+}
+
+func (i *sourceImporter) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, "", 0)
+}
+
+func (i *sourceImporter) ImportFrom(path, dir string, _ types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if info, err := os.Stat(filepath.Join(build.Default.GOROOT, "src", path)); err == nil && info.IsDir() {
+		return i.std.Import(path)
+	}
+
+	// The directory of a mock may not have been created yet
+	for dir != "" && dir != filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	p, err := i.loader.importPackage(path, dir)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := i.pkgs[p.dir]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+		return pkg, nil
+	}
+	i.pkgs[p.dir] = nil
+	// Errors in the imported packages are left to the compiler, only the
+	// errors they cause in the mock are reported
+	conf := types.Config{Importer: i, FakeImportC: true, Error: func(error) {}}
+	pkg, _ := conf.Check(path, i.loader.fset, p.astFiles(), nil)
+	i.pkgs[p.dir] = pkg
+	return pkg, nil
+}
+
+// astFiles returns the syntax trees of the files of the package
+func (p *pkg) astFiles() []*ast.File {
+	files := make([]*ast.File, len(p.files))
+	for i, f := range p.files {
+		files[i] = f.astFile
+	}
+	return files
+}
+
+// verifyMock type checks the source of a mock planned by p. A mock in the
+// package of the interface is checked together with the other files of the
+// package, replacing the file at its path, other mocks are checked on their
+// own. Unless it was rendered from a template, the mock must implement the
+// interface.
+func (f *Generator) verifyMock(p *mockPlan, src []byte) error {
+	iface := p.iface
+	fset := f.loader.fset
+	mockFile, err := parser.ParseFile(fset, p.output, src, parser.SkipObjectResolution)
+	if err != nil {
+		return fmt.Errorf("could not parse the mock of %s: %w", iface.Name, err)
+	}
+
+	files := []*ast.File{mockFile}
+	inPackage := p.inPackage()
+	if inPackage {
+		pkg, err := f.loader.loadDir(iface.Package.Dir, iface.Package.Name, strings.HasSuffix(p.output, "_test.go"))
+		if err != nil {
+			return err
+		}
+		for _, file := range pkg.files {
+			if overlayKey(file.path) != overlayKey(p.output) {
+				files = append(files, file.astFile)
+			}
+		}
+	}
+
+	imp := &sourceImporter{loader: f.loader, std: f.std, pkgs: map[string]*types.Package{}}
+	verifyErr := &VerifyError{Path: p.output, Interface: iface.Name}
+	conf := types.Config{
+		Importer:    imp,
+		FakeImportC: true,
+		Error: func(err error) {
+			var typeErr types.Error
+			if !errors.As(err, &typeErr) || fset.File(typeErr.Pos) != fset.File(mockFile.Pos()) {
+				return
+			}
+			pos := fset.Position(typeErr.Pos)
+			problem := VerifyProblem{
+				Position: positionOf(p.output, pos),
+				Msg:      strings.TrimSpace(typeErr.Msg),
+				Source:   sourceLine(src, pos.Line),
+			}
+			problem.setMethod(iface, declName(mockFile, typeErr.Pos))
+			verifyErr.Problems = append(verifyErr.Problems, problem)
+		},
+	}
+	pkgPath := p.pkg.Path
+	if pkgPath == "" {
+		pkgPath = p.pkg.Name
+	}
+	pkg, _ := conf.Check(pkgPath, fset, files, nil)
+
+	if len(verifyErr.Problems) == 0 && p.template == nil {
+		ifacePkg := pkg
+		if !inPackage && iface.Package.Path != "" {
+			ifacePkg, err = imp.ImportFrom(iface.Package.Path, filepath.Dir(p.output), 0)
+		}
+		if err == nil && ifacePkg != nil {
+			verifyErr.Problems = implementProblems(pkg, p.mock, ifacePkg, iface)
+		}
+	}
+	if len(verifyErr.Problems) > 0 {
+		return verifyErr
+	}
+	return nil
+}
+
+// implementProblems reports the methods of the interface that the mock named
+// mock in pkg does not implement. Generic mocks are instantiated with their
+// own type parameters, as is the interface.
+func implementProblems(pkg *types.Package, mock string, ifacePkg *types.Package, iface *model.Interface) []VerifyProblem {
+	mockObj, _ := pkg.Scope().Lookup(mock).(*types.TypeName)
+	ifaceObj, _ := ifacePkg.Scope().Lookup(iface.Name).(*types.TypeName)
+	if mockObj == nil || ifaceObj == nil {
+		return nil
+	}
+	mockType, ifaceType := mockObj.Type(), ifaceObj.Type()
+	if named, ok := mockType.(*types.Named); ok && named.TypeParams().Len() > 0 {
+		tparams := named.TypeParams()
+		args := make([]types.Type, tparams.Len())
+		for i := range args {
+			args[i] = tparams.At(i)
+		}
+		var err error
+		if mockType, err = types.Instantiate(nil, mockType, args, false); err != nil {
+			return nil
+		}
+		if ifaceType, err = types.Instantiate(nil, ifaceType, args, false); err != nil {
+			return nil
+		}
+	}
+	underlying, ok := ifaceType.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	missing, wrongType := types.MissingMethod(types.NewPointer(mockType), underlying, true)
+	if missing == nil {
+		return nil
+	}
+	problem := VerifyProblem{
+		Position: iface.Position,
+		Msg:      fmt.Sprintf("the mock %s does not implement %s, method %s is missing", mock, iface.Name, missing.Name()),
+	}
+	if wrongType {
+		problem.Msg = fmt.Sprintf("the mock %s does not implement %s, method %s has the wrong type", mock, iface.Name, missing.Name())
+	}
+	problem.setMethod(iface, missing.Name())
+	return []VerifyProblem{problem}
+}
+
+// declName returns the name of the declaration of the file containing pos, or
+// of the field when it is in a struct type
+func declName(file *ast.File, pos token.Pos) string {
+	for _, decl := range file.Decls {
+		if pos < decl.Pos() || pos >= decl.End() {
+			continue
+		}
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			return decl.Name.Name
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if pos < spec.Pos() || pos >= spec.End() {
+					continue
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if st, ok := spec.Type.(*ast.StructType); ok {
+						for _, field := range st.Fields.List {
+							if pos >= field.Pos() && pos < field.End() && len(field.Names) > 0 {
+								return field.Names[0].Name
+							}
+						}
+					}
+					return spec.Name.Name
+				case *ast.ValueSpec:
+					return spec.Names[0].Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// setMethod sets the method the problem is in from the name of the generated
+// declaration or field it is in, e.g. Get for GetFunc, getMutex or
+// GetCallCount. The method with the longest name that the declaration is
+// named after is chosen.
+func (p *VerifyProblem) setMethod(iface *model.Interface, decl string) {
+	if rest := strings.TrimPrefix(decl, "mock"); rest != decl && startsUpper(rest) {
+		decl = rest
+	}
+	var found *model.Method
+	for i, m := range iface.Methods {
+		if strings.HasPrefix(lowerFirst(decl), lowerFirst(m.Name)) && (found == nil || len(m.Name) > len(found.Name)) {
+			found = &iface.Methods[i]
+		}
+	}
+	if found != nil {
+		p.Method = found.Name
+		p.MethodPosition = found.Position
+	}
+}
+
+func startsUpper(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r)
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// sourceLine returns the line of src with the number, without indentation
+func sourceLine(src []byte, line int) string {
+	lines := bytes.Split(src, []byte("\n"))
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return string(bytes.TrimSpace(lines[line-1]))
+}
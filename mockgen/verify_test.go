@@ -0,0 +1,69 @@
+package mockgen
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestVerifyReportsMockLine(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n",
+		"store.go": "package store\n\ntype Store interface {\n\tLen() int\n\tGet(key string) int\n}\n",
+	})
+	path := filepath.Join(dir, "store.go")
+	output := filepath.Join(dir, "mock", "store.go")
+
+	tests := []struct {
+		name string
+		// broken is the line of the template with a type error
+		broken string
+		method string
+		msg    string
+	}{
+		{name: "in a method", broken: "func (FakeStore) Get(key string) int { return key }", method: "Get", msg: "cannot use key"},
+		{name: "outside of methods", broken: "var count int = \"none\"", msg: "cannot use \"none\""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("fake").Parse("package mock\n\n" +
+				"// FakeStore returns zero values\ntype FakeStore struct{}\n\n" +
+				"func (FakeStore) Len() int { return 0 }\n\n" +
+				test.broken + "\n"))
+			req := Request{Path: path, Name: "Store", Output: output}
+
+			// The line is found in the mock as generated without verifying it
+			files, err := New(WithTemplate(tmpl)).GenerateFiles(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line := strings.Count(string(files[0].Source[:strings.Index(string(files[0].Source), test.broken)]), "\n") + 1
+
+			_, err = New(WithTemplate(tmpl), WithVerify()).GenerateFiles(context.Background(), req)
+			var verifyErr *VerifyError
+			if !errors.As(err, &verifyErr) {
+				t.Fatalf("got %v, want a *VerifyError", err)
+			}
+			if len(verifyErr.Problems) != 1 {
+				t.Fatalf("got problems %+v, want one", verifyErr.Problems)
+			}
+			problem := verifyErr.Problems[0]
+			if problem.Position.Filename != output || problem.Position.Line != line {
+				t.Errorf("the problem is reported at %s:%d, want %s:%d", problem.Position.Filename, problem.Position.Line, output, line)
+			}
+			if problem.Source != test.broken || !strings.Contains(problem.Msg, test.msg) {
+				t.Errorf("got problem %q in %q, want %q in %q", problem.Msg, problem.Source, test.msg, test.broken)
+			}
+			if problem.Method != test.method {
+				t.Errorf("got method %q, want %q", problem.Method, test.method)
+			}
+			if test.method != "" && (problem.MethodPosition.Filename != path || problem.MethodPosition.Line != 5) {
+				t.Errorf("the method is reported at %+v, want %s:5", problem.MethodPosition, path)
+			}
+		})
+	}
+}
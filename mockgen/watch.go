@@ -0,0 +1,211 @@
+package mockgen
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WatchEvent is reported by Watch for each mock written, and for each error
+type WatchEvent struct {
+	// Path is the path of the mock, or of the file or directory an error
+	// occurred in when there is no mock
+	Path string
+	// Interface is the name of the mocked interface, empty if the error is
+	// not about a single interface
+	Interface string
+	// Err is set if the mock could not be generated
+	Err error
+}
+
+// Watch polls the Go files of the packages matched by the patterns, as given
+// to GenerateAll, and regenerates the mocks of interfaces whose method sets
+// change. Only interfaces with a mock at the path given by the layout of the
+// generator are regenerated, the mock keeps its name and the other declarations in its
+// file. Changes that do not alter the method set or the docs of a mocked
+// interface, like changes to function bodies, are skipped.
+//
+// Every mock written and every error is reported, errors do not stop the
+// watching. Watch returns when ctx is done, or with an error if the patterns
+// match no directories when starting.
+func (f *Generator) Watch(ctx context.Context, patterns []string, interval time.Duration, report func(WatchEvent)) error {
+	w := &watcher{
+		generator: f,
+		report:    report,
+		stamps:    map[string]stamp{},
+		sets:      map[string]string{},
+	}
+	if err := w.poll(ctx, patterns, true); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		// The patterns stop matching when a directory is removed, which is
+		// only reported once until it changes
+		err := w.poll(ctx, patterns, false)
+		if err != nil && err.Error() != w.pollErr {
+			report(WatchEvent{Err: err})
+		}
+		w.pollErr = ""
+		if err != nil {
+			w.pollErr = err.Error()
+		}
+	}
+}
+
+// watcher is the state of Watch between polls
+type watcher struct {
+	generator *Generator
+	report    func(WatchEvent)
+	// stamps are the modification times and sizes of the watched files
+	stamps map[string]stamp
+	// sets are the method sets and docs of the interfaces by the paths of
+	// their mocks
+	sets map[string]string
+	// pollErr is the error of the last poll
+	pollErr string
+}
+
+// poll finds added, removed or modified files and updates the mocks of the
+// packages when there are any. The first poll only records the method sets,
+// later polls report the errors of modified files.
+func (w *watcher) poll(ctx context.Context, patterns []string, first bool) error {
+	// Files are parsed again on each poll, by a generator with a new loader
+	g := *w.generator
+	g.loader = newLoader(g.overlay, g.logger)
+
+	dirs, err := g.expandPatterns(patterns)
+	if err != nil {
+		return err
+	}
+
+	stamps := map[string]stamp{}
+	changed := false
+	broken := map[string]bool{}
+	for _, dir := range dirs {
+		paths, err := g.loader.goFiles(dir, false)
+		if err != nil {
+			w.report(WatchEvent{Path: dir, Err: err})
+			broken[dir] = true
+			continue
+		}
+		modified := false
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			s := stampOf(info)
+			stamps[path] = s
+			if old, ok := w.stamps[path]; ok && old == s {
+				continue
+			}
+			modified = true
+			if _, err := g.loader.openFile(path); err != nil && !first {
+				w.report(WatchEvent{Path: path, Err: err})
+				broken[dir] = true
+			}
+		}
+		for path := range w.stamps {
+			if _, ok := stamps[path]; !ok && filepath.Dir(path) == dir {
+				modified = true
+			}
+		}
+		// Packages with files that are being edited are updated once the
+		// files can be parsed again
+		changed = changed || (modified && !broken[dir])
+	}
+	w.stamps = stamps
+	if !changed {
+		return nil
+	}
+
+	// Interfaces may embed interfaces of other packages, so the method sets
+	// of all packages are compared when any of them changes
+	for _, dir := range dirs {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !broken[dir] {
+			w.update(ctx, &g, dir, first)
+		}
+	}
+	return nil
+}
+
+// update regenerates the mocks of the interfaces in dir with changed method
+// sets, or only records the method sets if record is set
+func (w *watcher) update(ctx context.Context, g *Generator, dir string, record bool) {
+	p, err := g.loader.loadDir(dir, "", false)
+	if err != nil {
+		w.report(WatchEvent{Path: dir, Err: err})
+		return
+	}
+	if p.name == "main" {
+		return
+	}
+
+	for _, file := range p.files {
+		for _, spec := range file.interfaces() {
+			if !ast.IsExported(spec.Name.Name) {
+				continue
+			}
+			iface, err := g.loader.parseInterface(file, spec)
+			if errors.Is(err, ErrUnsupportedType) {
+				continue
+			}
+			var output string
+			if err == nil {
+				output, err = g.outputPath(iface, dir)
+			}
+			if err != nil {
+				if !record {
+					w.report(WatchEvent{Path: file.path, Interface: spec.Name.Name, Err: err})
+				}
+				continue
+			}
+
+			set := iface.MethodSet() + "\n" + interfaceDocs(iface)
+			old, ok := w.sets[output]
+			w.sets[output] = set
+			if record || (ok && old == set) {
+				continue
+			}
+
+			mock, found := "", false
+			for _, m := range parseMocks(output) {
+				if m.iface == iface.Name {
+					mock, found = m.name, true
+				}
+			}
+			if !found {
+				g.logger.Debug("skipping interface without mock", "name", iface.Name, "mock", output)
+				continue
+			}
+
+			files, err := g.GenerateFiles(ctx, Request{
+				Path:     file.path,
+				Name:     iface.Name,
+				Output:   output,
+				Merge:    g.template == nil,
+				MockName: mock,
+			})
+			for _, generated := range files {
+				if err == nil {
+					err = os.WriteFile(generated.Path, generated.Source, 0660)
+				}
+			}
+			w.report(WatchEvent{Path: output, Interface: iface.Name, Err: err})
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package mockgen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWatchRegeneratesEmbeddingInterfaces(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n",
+		"a/a.go":   "package a\n\ntype A interface {\n\tGet() int\n}\n",
+		"b/b.go":   "package b\n\nimport \"example.com/m/a\"\n\ntype B interface {\n\ta.A\n}\n",
+		"c/c.go":   "package c\n\ntype C interface {\n\tClose() error\n}\n",
+		"a/doc.go": "// Package a is embedded by b\npackage a\n",
+	})
+	patterns := []string{filepath.Join(dir, "...")}
+
+	g := New()
+	files, err := g.GenerateAll(context.Background(), patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.WriteFiles(files); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []WatchEvent
+	w := &watcher{
+		generator: g,
+		report:    func(event WatchEvent) { events = append(events, event) },
+		stamps:    map[string]stamp{},
+		sets:      map[string]string{},
+	}
+	if err := w.poll(context.Background(), patterns, true); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"a/a.go": "package a\n\ntype A interface {\n\tGet() int\n\tPut(n int)\n}\n",
+	})
+	if err := w.poll(context.Background(), patterns, false); err != nil {
+		t.Fatal(err)
+	}
+
+	regenerated := map[string]bool{}
+	for _, event := range events {
+		if event.Err != nil {
+			t.Errorf("%s (%s): %v", event.Path, event.Interface, event.Err)
+		}
+		regenerated[event.Interface] = true
+	}
+	if !regenerated["A"] || !regenerated["B"] || regenerated["C"] || len(events) != 2 {
+		t.Fatalf("regenerated %v, want A and B", events)
+	}
+	src, err := os.ReadFile(filepath.Join(dir, "b", "mock", "b.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "func (m *B) Put(n int)") {
+		t.Errorf("the mock of B does not mock Put:\n%s", src)
+	}
+}
+
+func TestWatchRemovedMethod(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"a/a.go": "package a\n\nimport \"io\"\n\ntype A interface {\n\tGet() int\n\tRead(r io.Reader)\n}\n",
+	})
+	patterns := []string{filepath.Join(dir, "...")}
+
+	g := New(WithVerify())
+	files, err := g.GenerateAll(context.Background(), patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.WriteFiles(files); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []WatchEvent
+	w := &watcher{
+		generator: g,
+		report:    func(event WatchEvent) { events = append(events, event) },
+		stamps:    map[string]stamp{},
+		sets:      map[string]string{},
+	}
+	if err := w.poll(context.Background(), patterns, true); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"a/a.go": "package a\n\ntype A interface {\n\tGet() int\n}\n",
+	})
+	if err := w.poll(context.Background(), patterns, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0].Err != nil {
+		t.Fatalf("got events %v, want A to be regenerated", events)
+	}
+	src, err := os.ReadFile(filepath.Join(dir, "a", "mock", "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(src), "Read") || strings.Contains(string(src), "\"io\"") {
+		t.Errorf("the mock of A still mocks Read:\n%s", src)
+	}
+}